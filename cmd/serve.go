@@ -4,12 +4,17 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"os/signal"
+	"strings"
+	"syscall"
 
-	"github.com/aws/aws-sdk-go-v2/config"
 	"github.com/spf13/cobra"
 
 	"github.com/cldmnky/dyndnsr53/pkg/provider"
-	"github.com/cldmnky/dyndnsr53/pkg/providers/route53"
+	_ "github.com/cldmnky/dyndnsr53/pkg/providers/cloudflare"
+	_ "github.com/cldmnky/dyndnsr53/pkg/providers/digitalocean"
+	_ "github.com/cldmnky/dyndnsr53/pkg/providers/exec"
+	_ "github.com/cldmnky/dyndnsr53/pkg/providers/route53"
 	"github.com/cldmnky/dyndnsr53/pkg/server"
 )
 
@@ -31,35 +36,48 @@ var serveCmd = &cobra.Command{
 		var err error
 
 		switch providerType {
-		case "route53":
-			if zoneID == "" {
-				fmt.Fprintf(os.Stderr, "Error: --zone-id is required when using route53 provider\n")
-				os.Exit(1)
+		case "none", "":
+			fmt.Printf("No provider configured - running in test mode\n")
+		default:
+			// Every provider package registers a factory with
+			// provider.DefaultRegistry from its own init(), the same way
+			// lego builds a challenge.Provider from environment variables
+			// via NewDNSChallengeProviderByName. --zone-id and --exec-path
+			// are provider specific, so thread them through as the env vars
+			// their packages read.
+			if zoneID != "" {
+				os.Setenv("AWS_HOSTED_ZONE_ID", zoneID)
 			}
-			// Load AWS config
-			ctx := context.Background()
-			cfg, err := config.LoadDefaultConfig(ctx)
-			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error loading AWS config: %v\n", err)
-				os.Exit(1)
+			if execPath, _ := cmd.Flags().GetString("exec-path"); execPath != "" {
+				os.Setenv("EXEC_PATH", execPath)
+			}
+			if execTimeout, _ := cmd.Flags().GetDuration("exec-timeout"); execTimeout > 0 {
+				os.Setenv("EXEC_TIMEOUT", execTimeout.String())
 			}
-			p, err = route53.NewRoute53Provider(ctx, zoneID, cfg)
+
+			ctx := context.Background()
+			p, err = provider.DefaultRegistry.NewProviderByName(ctx, providerType)
 			if err != nil {
-				fmt.Fprintf(os.Stderr, "Error creating Route53 provider: %v\n", err)
+				fmt.Fprintf(os.Stderr, "Error creating %s provider: %v\n", providerType, err)
+				fmt.Fprintf(os.Stderr, "Supported providers: %s, none\n", strings.Join(provider.DefaultRegistry.Names(), ", "))
 				os.Exit(1)
 			}
-			fmt.Printf("Using Route53 provider with zone: %s\n", zoneID)
-		case "none", "":
-			fmt.Printf("No provider configured - running in test mode\n")
-			p = nil
-		default:
-			fmt.Fprintf(os.Stderr, "Error: unsupported provider type: %s\n", providerType)
-			fmt.Fprintf(os.Stderr, "Supported providers: route53, none\n")
+			fmt.Printf("Using %s provider\n", providerType)
+		}
+
+		// Build the credential store. An --auth-file holds one or more
+		// users, each with their own hostname allow-list, and is
+		// hot-reloadable via SIGHUP; without one, fall back to a single
+		// "user"/"pass" user permitted to update any hostname.
+		authFile, _ := cmd.Flags().GetString("auth-file")
+		credStore, err := newCredentialStore(authFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error loading auth file: %v\n", err)
 			os.Exit(1)
 		}
 
 		// Create and start server
-		s := server.NewServer(p)
+		s := server.NewServer(p, credStore)
 		fmt.Printf("Starting server on %s...\n", addr)
 		err = s.StartServer(addr)
 		if err != nil {
@@ -71,7 +89,40 @@ var serveCmd = &cobra.Command{
 
 func init() {
 	serveCmd.Flags().StringP("listen", "l", ":8080", "Address to listen on (default :8080)")
-	serveCmd.Flags().StringP("provider", "p", "none", "DNS provider to use (route53, none)")
-	serveCmd.Flags().String("zone-id", "", "Route53 hosted zone ID (required for route53 provider)")
+	serveCmd.Flags().StringP("provider", "p", "none", "DNS provider to use (route53, cloudflare, digitalocean, exec, none)")
+	serveCmd.Flags().String("zone-id", "", "Route53 hosted zone ID (route53 provider only)")
+	serveCmd.Flags().String("exec-path", "", "Path to the external program to invoke for updates (exec provider only)")
+	serveCmd.Flags().Duration("exec-timeout", 0, "Timeout for each exec provider invocation (default 30s)")
+	serveCmd.Flags().String("auth-file", "", "Path to a YAML file of users and their hostname allow-lists (default: single user/pass allowed to update any hostname)")
 	rootCmd.AddCommand(serveCmd)
 }
+
+// newCredentialStore builds the server's CredentialStore. Given an
+// --auth-file it loads it and reloads it on SIGHUP; otherwise it falls back
+// to a single hardcoded user permitted to update any hostname, preserving
+// the server's previous behavior.
+func newCredentialStore(authFile string) (server.CredentialStore, error) {
+	if authFile == "" {
+		fmt.Println("No --auth-file configured, falling back to a single user/pass user allowed to update any hostname")
+		return server.NewStaticCredentialStore("user", "pass")
+	}
+
+	store, err := server.NewFileCredentialStore(authFile)
+	if err != nil {
+		return nil, err
+	}
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGHUP)
+	go func() {
+		for range sigCh {
+			if err := store.Reload(); err != nil {
+				fmt.Fprintf(os.Stderr, "Error reloading auth file: %v\n", err)
+				continue
+			}
+			fmt.Println("Reloaded auth file")
+		}
+	}()
+
+	return store, nil
+}
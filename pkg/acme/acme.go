@@ -0,0 +1,52 @@
+// Package acme adapts a provider.TXTProvider to lego's challenge.Provider
+// interface, so dyndnsr53 can be embedded directly as an ACME DNS-01 solver
+// instead of only being driven over HTTP.
+package acme
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"fmt"
+	"strings"
+
+	"github.com/cldmnky/dyndnsr53/pkg/provider"
+)
+
+// challengePrefix is the DNS label ACME DNS-01 challenges are published
+// under, per RFC 8555 section 8.4.
+const challengePrefix = "_acme-challenge."
+
+// Solver adapts a provider.TXTProvider to lego's challenge.Provider
+// interface (Present/CleanUp).
+type Solver struct {
+	txt provider.TXTProvider
+}
+
+// NewSolver creates a Solver backed by txt.
+func NewSolver(txt provider.TXTProvider) *Solver {
+	return &Solver{txt: txt}
+}
+
+// Present implements lego's challenge.Provider: it publishes the DNS-01 key
+// authorization digest for domain as a TXT record under
+// _acme-challenge.<domain>.
+func (s *Solver) Present(domain, token, keyAuth string) error {
+	fqdn, value := ChallengeRecord(domain, keyAuth)
+	return s.txt.PresentTXT(fqdn, value)
+}
+
+// CleanUp implements lego's challenge.Provider: it removes the TXT record
+// published by Present.
+func (s *Solver) CleanUp(domain, token, keyAuth string) error {
+	fqdn, value := ChallengeRecord(domain, keyAuth)
+	return s.txt.CleanupTXT(fqdn, value)
+}
+
+// ChallengeRecord computes the _acme-challenge FQDN and TXT value for
+// domain and keyAuth, per RFC 8555 section 8.4.
+func ChallengeRecord(domain, keyAuth string) (fqdn, value string) {
+	digest := sha256.Sum256([]byte(keyAuth))
+	value = base64.RawURLEncoding.EncodeToString(digest[:])
+	fqdn = fmt.Sprintf("%s%s.", challengePrefix, strings.TrimSuffix(domain, "."))
+	return fqdn, value
+}
@@ -0,0 +1,49 @@
+package acme
+
+import "testing"
+
+type fakeTXTProvider struct {
+	presented map[string]string
+}
+
+func (f *fakeTXTProvider) PresentTXT(fqdn, value string) error {
+	if f.presented == nil {
+		f.presented = make(map[string]string)
+	}
+	f.presented[fqdn] = value
+	return nil
+}
+
+func (f *fakeTXTProvider) CleanupTXT(fqdn, value string) error {
+	delete(f.presented, fqdn)
+	return nil
+}
+
+func TestChallengeRecord(t *testing.T) {
+	fqdn, value := ChallengeRecord("home.example.com", "token.keyAuthThumbprint")
+	if fqdn != "_acme-challenge.home.example.com." {
+		t.Errorf("unexpected fqdn: %q", fqdn)
+	}
+	if value == "" {
+		t.Error("expected a non-empty TXT value")
+	}
+}
+
+func TestSolver_PresentCleanUp(t *testing.T) {
+	fake := &fakeTXTProvider{}
+	solver := NewSolver(fake)
+
+	if err := solver.Present("home.example.com", "token", "keyAuth"); err != nil {
+		t.Fatalf("Present() error = %v", err)
+	}
+	if len(fake.presented) != 1 {
+		t.Fatalf("expected 1 presented record, got %d", len(fake.presented))
+	}
+
+	if err := solver.CleanUp("home.example.com", "token", "keyAuth"); err != nil {
+		t.Fatalf("CleanUp() error = %v", err)
+	}
+	if len(fake.presented) != 0 {
+		t.Fatalf("expected record to be cleaned up, got %v", fake.presented)
+	}
+}
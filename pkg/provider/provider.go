@@ -1,8 +1,102 @@
 package provider
 
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"sort"
+	"strings"
+)
+
 // Provider defines the interface for DNS providers that can update records.
 type Provider interface {
-	// UpdateRecord updates the DNS record for the given FQDN to the specified IP address.
+	// UpdateRecord upserts the DNS records for the given FQDN to addrs, one
+	// record per address family present (A for IPv4, AAAA for IPv6).
 	// Returns nil on success, or an error on failure.
-	UpdateRecord(fqdn, ip string) error
+	UpdateRecord(fqdn string, addrs []netip.Addr) error
+}
+
+// TXTProvider is implemented by backends that can present and clean up TXT
+// records, the capability an ACME DNS-01 solver needs - the same role
+// lego's challenge.Provider interface plays for its DNS providers. It is
+// kept separate from Provider because not every backend supports it.
+type TXTProvider interface {
+	// PresentTXT upserts a TXT record for fqdn containing value, merging it
+	// into any values already present on the record.
+	PresentTXT(fqdn, value string) error
+	// CleanupTXT removes value from the TXT record for fqdn, deleting the
+	// record entirely once no values remain.
+	CleanupTXT(fqdn, value string) error
+}
+
+// RequestContext carries per-request metadata that a backend may want
+// threaded through to UpdateRecord, such as the exec provider passing it to
+// the external program via environment variables.
+type RequestContext struct {
+	// Remote is the requesting client's address, e.g. an HTTP RemoteAddr.
+	Remote string
+	// User is the authenticated username that made the request.
+	User string
+	// Output, if non-nil, receives any output a backend captures while
+	// servicing the request (e.g. the exec provider's combined
+	// stdout/stderr), so callers can surface it in a structured log
+	// regardless of whether the call succeeds or fails.
+	Output *strings.Builder
+}
+
+// ContextualProvider is implemented by backends that want RequestContext
+// threaded through to their update logic. It is kept separate from
+// Provider, the same way TXTProvider is, because most backends have no use
+// for it.
+type ContextualProvider interface {
+	// UpdateRecordWithContext behaves like Provider.UpdateRecord, but also
+	// receives the RequestContext for the triggering request.
+	UpdateRecordWithContext(fqdn string, addrs []netip.Addr, reqCtx RequestContext) error
+}
+
+// Factory constructs a Provider from its environment, the same way lego's
+// per-backend constructors build a challenge.Provider from env vars such as
+// CLOUDFLARE_API_TOKEN or DIGITALOCEAN_AUTH_TOKEN.
+type Factory func(ctx context.Context) (Provider, error)
+
+// Registry maps short provider names (e.g. "route53", "cloudflare") to the
+// factory that builds them, so callers like serveCmd can construct a
+// Provider by name without a hardcoded switch statement.
+type Registry struct {
+	factories map[string]Factory
 }
+
+// NewRegistry creates an empty Registry.
+func NewRegistry() *Registry {
+	return &Registry{factories: make(map[string]Factory)}
+}
+
+// Register adds a factory under name, overwriting any previous registration.
+// Provider packages call this from their own init() function.
+func (r *Registry) Register(name string, factory Factory) {
+	r.factories[name] = factory
+}
+
+// NewProviderByName builds a Provider using the factory registered under
+// name, analogous to lego's NewDNSChallengeProviderByName.
+func (r *Registry) NewProviderByName(ctx context.Context, name string) (Provider, error) {
+	factory, ok := r.factories[name]
+	if !ok {
+		return nil, fmt.Errorf("unsupported provider type: %s", name)
+	}
+	return factory(ctx)
+}
+
+// Names returns the sorted list of registered provider names.
+func (r *Registry) Names() []string {
+	names := make([]string, 0, len(r.factories))
+	for name := range r.factories {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// DefaultRegistry is the process-wide registry that provider packages
+// register themselves into from init().
+var DefaultRegistry = NewRegistry()
@@ -0,0 +1,175 @@
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/cloudflare/cloudflare-go"
+
+	"github.com/cldmnky/dyndnsr53/pkg/provider"
+)
+
+func init() {
+	provider.DefaultRegistry.Register("cloudflare", func(ctx context.Context) (provider.Provider, error) {
+		return NewProviderFromEnv(ctx)
+	})
+}
+
+// Provider implements the Provider interface for Cloudflare DNS.
+var _ provider.Provider = (*Provider)(nil)
+
+// cloudflareAPI is the subset of the Cloudflare client Provider depends on,
+// so tests can substitute a fake implementation instead of hitting the
+// Cloudflare API.
+type cloudflareAPI interface {
+	ZoneIDByName(zoneName string) (string, error)
+	ListDNSRecords(ctx context.Context, rc *cloudflare.ResourceContainer, params cloudflare.ListDNSRecordsParams) ([]cloudflare.DNSRecord, *cloudflare.ResultInfo, error)
+	UpdateDNSRecord(ctx context.Context, rc *cloudflare.ResourceContainer, params cloudflare.UpdateDNSRecordParams) (cloudflare.DNSRecord, error)
+	CreateDNSRecord(ctx context.Context, rc *cloudflare.ResourceContainer, params cloudflare.CreateDNSRecordParams) (cloudflare.DNSRecord, error)
+	DeleteDNSRecord(ctx context.Context, rc *cloudflare.ResourceContainer, recordID string) error
+}
+
+// Provider represents a Cloudflare DNS provider bound to a single zone.
+type Provider struct {
+	api      cloudflareAPI
+	zoneID   string
+	zoneName string
+}
+
+// NewProvider creates a new Cloudflare Provider for the given zone.
+func NewProvider(ctx context.Context, apiToken, zoneName string) (*Provider, error) {
+	if apiToken == "" {
+		return nil, fmt.Errorf("apiToken must not be empty")
+	}
+	if zoneName == "" {
+		return nil, fmt.Errorf("zoneName must not be empty")
+	}
+
+	api, err := cloudflare.NewWithAPIToken(apiToken)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Cloudflare client: %w", err)
+	}
+
+	return newProvider(api, zoneName)
+}
+
+// newProvider resolves zoneName's zone ID against api and builds a Provider.
+// Split out from NewProvider so tests can supply a fake cloudflareAPI.
+func newProvider(api cloudflareAPI, zoneName string) (*Provider, error) {
+	zoneID, err := api.ZoneIDByName(zoneName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve zone %s: %w", zoneName, err)
+	}
+
+	return &Provider{api: api, zoneID: zoneID, zoneName: zoneName}, nil
+}
+
+// NewProviderFromEnv builds a Provider from the CLOUDFLARE_API_TOKEN and
+// CLOUDFLARE_ZONE_NAME environment variables, the way lego configures its
+// Cloudflare DNS challenge provider.
+func NewProviderFromEnv(ctx context.Context) (*Provider, error) {
+	return NewProvider(ctx, os.Getenv("CLOUDFLARE_API_TOKEN"), os.Getenv("CLOUDFLARE_ZONE_NAME"))
+}
+
+// UpdateRecord reconciles the A and/or AAAA records for the given FQDN
+// against addrs. Cloudflare models each address as its own record rather
+// than one record holding multiple values, so every address within a
+// family is reconciled together: existing records are updated in place,
+// extra addresses get new records, and extra existing records are deleted,
+// the same grouping Route53's buildChanges does for a single multi-value
+// ResourceRecordSet.
+func (p *Provider) UpdateRecord(fqdn string, addrs []netip.Addr) error {
+	if fqdn == "" || len(addrs) == 0 {
+		return fmt.Errorf("fqdn and addrs must not be empty")
+	}
+	if err := p.validateFQDN(fqdn); err != nil {
+		return err
+	}
+
+	v4, v6 := groupByFamily(addrs)
+	if len(v4) > 0 {
+		if err := p.reconcileRecords(fqdn, "A", v4); err != nil {
+			return err
+		}
+	}
+	if len(v6) > 0 {
+		if err := p.reconcileRecords(fqdn, "AAAA", v6); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// groupByFamily splits addrs into their string values by address family.
+func groupByFamily(addrs []netip.Addr) (v4, v6 []string) {
+	for _, addr := range addrs {
+		if addr.Is6() && !addr.Is4In6() {
+			v6 = append(v6, addr.String())
+		} else {
+			v4 = append(v4, addr.String())
+		}
+	}
+	return v4, v6
+}
+
+// reconcileRecords makes the fqdn/recordType records match values exactly:
+// reusing existing record IDs where possible, creating records for any
+// extra values, and deleting any existing records beyond len(values).
+func (p *Provider) reconcileRecords(fqdn, recordType string, values []string) error {
+	ctx := context.Background()
+	rc := cloudflare.ZoneIdentifier(p.zoneID)
+
+	existing, _, err := p.api.ListDNSRecords(ctx, rc, cloudflare.ListDNSRecordsParams{
+		Type: recordType,
+		Name: fqdn,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to list DNS records: %w", err)
+	}
+
+	for i, content := range values {
+		if i < len(existing) {
+			if _, err := p.api.UpdateDNSRecord(ctx, rc, cloudflare.UpdateDNSRecordParams{
+				ID:      existing[i].ID,
+				Type:    recordType,
+				Name:    fqdn,
+				Content: content,
+				TTL:     60,
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, err := p.api.CreateDNSRecord(ctx, rc, cloudflare.CreateDNSRecordParams{
+			Type:    recordType,
+			Name:    fqdn,
+			Content: content,
+			TTL:     60,
+		}); err != nil {
+			return err
+		}
+	}
+
+	for _, stale := range existing[min(len(values), len(existing)):] {
+		if err := p.api.DeleteDNSRecord(ctx, rc, stale.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateFQDN ensures the FQDN belongs to the configured zone.
+func (p *Provider) validateFQDN(fqdn string) error {
+	cleanFQDN := strings.TrimSuffix(fqdn, ".")
+	cleanZone := strings.TrimSuffix(p.zoneName, ".")
+
+	if !strings.HasSuffix(cleanFQDN, "."+cleanZone) && cleanFQDN != cleanZone {
+		return fmt.Errorf("FQDN %s does not belong to zone %s", fqdn, p.zoneName)
+	}
+
+	return nil
+}
@@ -0,0 +1,170 @@
+package cloudflare
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"testing"
+
+	"github.com/cloudflare/cloudflare-go"
+)
+
+// fakeCloudflare is a minimal in-memory stand-in for cloudflareAPI, keyed by
+// zone name and by "type/name" for records, used to test UpdateRecord
+// without calling the Cloudflare API.
+type fakeCloudflare struct {
+	zones   map[string]string // zone name -> zone ID
+	records map[string][]cloudflare.DNSRecord
+	nextID  int
+}
+
+func (f *fakeCloudflare) ZoneIDByName(zoneName string) (string, error) {
+	if id, ok := f.zones[zoneName]; ok {
+		return id, nil
+	}
+	return "", fmt.Errorf("zone %q not found", zoneName)
+}
+
+func (f *fakeCloudflare) ListDNSRecords(_ context.Context, _ *cloudflare.ResourceContainer, params cloudflare.ListDNSRecordsParams) ([]cloudflare.DNSRecord, *cloudflare.ResultInfo, error) {
+	return f.records[params.Type+"/"+params.Name], &cloudflare.ResultInfo{}, nil
+}
+
+func (f *fakeCloudflare) UpdateDNSRecord(_ context.Context, _ *cloudflare.ResourceContainer, params cloudflare.UpdateDNSRecordParams) (cloudflare.DNSRecord, error) {
+	key := params.Type + "/" + params.Name
+	for i, rec := range f.records[key] {
+		if rec.ID == params.ID {
+			f.records[key][i].Content = params.Content
+			return f.records[key][i], nil
+		}
+	}
+	return cloudflare.DNSRecord{}, fmt.Errorf("record %s not found", params.ID)
+}
+
+func (f *fakeCloudflare) CreateDNSRecord(_ context.Context, _ *cloudflare.ResourceContainer, params cloudflare.CreateDNSRecordParams) (cloudflare.DNSRecord, error) {
+	f.nextID++
+	rec := cloudflare.DNSRecord{ID: fmt.Sprintf("record-%d", f.nextID), Type: params.Type, Name: params.Name, Content: params.Content, TTL: params.TTL}
+	key := params.Type + "/" + params.Name
+	f.records[key] = append(f.records[key], rec)
+	return rec, nil
+}
+
+func (f *fakeCloudflare) DeleteDNSRecord(_ context.Context, _ *cloudflare.ResourceContainer, recordID string) error {
+	for key, recs := range f.records {
+		for i, rec := range recs {
+			if rec.ID == recordID {
+				f.records[key] = append(recs[:i], recs[i+1:]...)
+				return nil
+			}
+		}
+	}
+	return fmt.Errorf("record %s not found", recordID)
+}
+
+func newFakeCloudflare() *fakeCloudflare {
+	return &fakeCloudflare{records: map[string][]cloudflare.DNSRecord{}}
+}
+
+func contentsOf(recs []cloudflare.DNSRecord) []string {
+	contents := make([]string, len(recs))
+	for i, r := range recs {
+		contents[i] = r.Content
+	}
+	return contents
+}
+
+func TestUpdateRecord_ValidationErrors(t *testing.T) {
+	p := &Provider{api: newFakeCloudflare(), zoneID: "Z1", zoneName: "example.com"}
+
+	if err := p.UpdateRecord("", []netip.Addr{netip.MustParseAddr("1.2.3.4")}); err == nil {
+		t.Fatal("expected error for empty fqdn")
+	}
+	if err := p.UpdateRecord("home.example.com", nil); err == nil {
+		t.Fatal("expected error for empty addrs")
+	}
+	if err := p.UpdateRecord("home.other.com", []netip.Addr{netip.MustParseAddr("1.2.3.4")}); err == nil {
+		t.Fatal("expected error for fqdn outside the configured zone")
+	}
+}
+
+func TestUpdateRecord_DualStack(t *testing.T) {
+	fake := newFakeCloudflare()
+	p := &Provider{api: fake, zoneID: "Z1", zoneName: "example.com"}
+
+	err := p.UpdateRecord("home.example.com", []netip.Addr{
+		netip.MustParseAddr("1.2.3.4"),
+		netip.MustParseAddr("2001:db8::1"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	a := fake.records["A/home.example.com"]
+	if len(a) != 1 || a[0].Content != "1.2.3.4" {
+		t.Errorf("expected a single A record 1.2.3.4, got %+v", a)
+	}
+	aaaa := fake.records["AAAA/home.example.com"]
+	if len(aaaa) != 1 || aaaa[0].Content != "2001:db8::1" {
+		t.Errorf("expected a single AAAA record 2001:db8::1, got %+v", aaaa)
+	}
+}
+
+func TestUpdateRecord_UpdatesExistingRecord(t *testing.T) {
+	fake := newFakeCloudflare()
+	fake.records["A/home.example.com"] = []cloudflare.DNSRecord{
+		{ID: "existing", Type: "A", Name: "home.example.com", Content: "1.1.1.1"},
+	}
+	p := &Provider{api: fake, zoneID: "Z1", zoneName: "example.com"}
+
+	if err := p.UpdateRecord("home.example.com", []netip.Addr{netip.MustParseAddr("2.2.2.2")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	recs := fake.records["A/home.example.com"]
+	if len(recs) != 1 || recs[0].ID != "existing" {
+		t.Errorf("expected the existing record to be updated in place, got %+v", recs)
+	}
+	if recs[0].Content != "2.2.2.2" {
+		t.Errorf("expected content 2.2.2.2, got %q", recs[0].Content)
+	}
+}
+
+func TestUpdateRecord_MultipleAddressesPerFamily(t *testing.T) {
+	fake := newFakeCloudflare()
+	fake.records["A/home.example.com"] = []cloudflare.DNSRecord{
+		{ID: "existing", Type: "A", Name: "home.example.com", Content: "9.9.9.9"},
+	}
+	p := &Provider{api: fake, zoneID: "Z1", zoneName: "example.com"}
+
+	err := p.UpdateRecord("home.example.com", []netip.Addr{
+		netip.MustParseAddr("1.2.3.4"),
+		netip.MustParseAddr("1.2.3.5"),
+		netip.MustParseAddr("1.2.3.6"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := contentsOf(fake.records["A/home.example.com"])
+	if len(got) != 3 {
+		t.Fatalf("expected all 3 addresses to be present as separate records, got %v", got)
+	}
+	if fake.records["A/home.example.com"][0].ID != "existing" {
+		t.Errorf("expected the first address to reuse the existing record, got %+v", fake.records["A/home.example.com"][0])
+	}
+
+	// A subsequent update with fewer addresses should delete the extras
+	// rather than leaving stale records behind.
+	if err := p.UpdateRecord("home.example.com", []netip.Addr{netip.MustParseAddr("1.2.3.4")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if recs := fake.records["A/home.example.com"]; len(recs) != 1 {
+		t.Errorf("expected stale records to be deleted, got %+v", recs)
+	}
+}
+
+func TestNewProvider_ZoneNotFound(t *testing.T) {
+	fake := &fakeCloudflare{zones: map[string]string{}}
+	if _, err := newProvider(fake, "example.com"); err == nil {
+		t.Fatal("expected error when the zone can't be resolved")
+	}
+}
@@ -0,0 +1,161 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"os"
+	"strings"
+
+	"github.com/digitalocean/godo"
+
+	"github.com/cldmnky/dyndnsr53/pkg/provider"
+)
+
+func init() {
+	provider.DefaultRegistry.Register("digitalocean", func(ctx context.Context) (provider.Provider, error) {
+		return NewProviderFromEnv(ctx)
+	})
+}
+
+// Provider implements the Provider interface for DigitalOcean DNS.
+var _ provider.Provider = (*Provider)(nil)
+
+// domainsAPI is the subset of godo.DomainsService Provider depends on, so
+// tests can substitute a fake implementation instead of hitting the
+// DigitalOcean API.
+type domainsAPI interface {
+	RecordsByTypeAndName(ctx context.Context, domain, recordType, name string, opt *godo.ListOptions) ([]godo.DomainRecord, *godo.Response, error)
+	EditRecord(ctx context.Context, domain string, id int, editRequest *godo.DomainRecordEditRequest) (*godo.DomainRecord, *godo.Response, error)
+	CreateRecord(ctx context.Context, domain string, createRequest *godo.DomainRecordEditRequest) (*godo.DomainRecord, *godo.Response, error)
+	DeleteRecord(ctx context.Context, domain string, id int) (*godo.Response, error)
+}
+
+// Provider represents a DigitalOcean DNS provider bound to a single domain.
+type Provider struct {
+	domains    domainsAPI
+	domainName string
+}
+
+// NewProvider creates a new DigitalOcean Provider for the given domain.
+func NewProvider(ctx context.Context, authToken, domainName string) (*Provider, error) {
+	if authToken == "" {
+		return nil, fmt.Errorf("authToken must not be empty")
+	}
+	if domainName == "" {
+		return nil, fmt.Errorf("domainName must not be empty")
+	}
+
+	return &Provider{
+		domains:    godo.NewFromToken(authToken).Domains,
+		domainName: domainName,
+	}, nil
+}
+
+// NewProviderFromEnv builds a Provider from the DIGITALOCEAN_AUTH_TOKEN and
+// DIGITALOCEAN_DOMAIN environment variables, the way lego configures its
+// DigitalOcean DNS challenge provider.
+func NewProviderFromEnv(ctx context.Context) (*Provider, error) {
+	return NewProvider(ctx, os.Getenv("DIGITALOCEAN_AUTH_TOKEN"), os.Getenv("DIGITALOCEAN_DOMAIN"))
+}
+
+// UpdateRecord reconciles the A and/or AAAA records for the given FQDN
+// against addrs. DigitalOcean models each address as its own record rather
+// than one record holding multiple values, so every address within a
+// family is reconciled together: existing records are updated in place,
+// extra addresses get new records, and extra existing records are deleted,
+// the same grouping Route53's buildChanges does for a single multi-value
+// ResourceRecordSet.
+func (p *Provider) UpdateRecord(fqdn string, addrs []netip.Addr) error {
+	if fqdn == "" || len(addrs) == 0 {
+		return fmt.Errorf("fqdn and addrs must not be empty")
+	}
+
+	name, err := p.recordName(fqdn)
+	if err != nil {
+		return err
+	}
+
+	v4, v6 := groupByFamily(addrs)
+	if len(v4) > 0 {
+		if err := p.reconcileRecords(name, "A", v4); err != nil {
+			return err
+		}
+	}
+	if len(v6) > 0 {
+		if err := p.reconcileRecords(name, "AAAA", v6); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// groupByFamily splits addrs into their string values by address family.
+func groupByFamily(addrs []netip.Addr) (v4, v6 []string) {
+	for _, addr := range addrs {
+		if addr.Is6() && !addr.Is4In6() {
+			v6 = append(v6, addr.String())
+		} else {
+			v4 = append(v4, addr.String())
+		}
+	}
+	return v4, v6
+}
+
+// reconcileRecords makes the name/recordType records match values exactly:
+// reusing existing record IDs where possible, creating records for any
+// extra values, and deleting any existing records beyond len(values).
+func (p *Provider) reconcileRecords(name, recordType string, values []string) error {
+	ctx := context.Background()
+	existing, _, err := p.domains.RecordsByTypeAndName(ctx, p.domainName, recordType, name, &godo.ListOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to list DNS records: %w", err)
+	}
+
+	for i, data := range values {
+		if i < len(existing) {
+			if _, _, err := p.domains.EditRecord(ctx, p.domainName, existing[i].ID, &godo.DomainRecordEditRequest{
+				Type: recordType,
+				Name: name,
+				Data: data,
+				TTL:  60,
+			}); err != nil {
+				return err
+			}
+			continue
+		}
+		if _, _, err := p.domains.CreateRecord(ctx, p.domainName, &godo.DomainRecordEditRequest{
+			Type: recordType,
+			Name: name,
+			Data: data,
+			TTL:  60,
+		}); err != nil {
+			return err
+		}
+	}
+
+	for _, stale := range existing[min(len(values), len(existing)):] {
+		if _, err := p.domains.DeleteRecord(ctx, p.domainName, stale.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// recordName strips the domain suffix from fqdn to get the DigitalOcean
+// record name (e.g. "home" for "home.example.com" in domain "example.com"),
+// returning "@" for the apex record.
+func (p *Provider) recordName(fqdn string) (string, error) {
+	cleanFQDN := strings.TrimSuffix(fqdn, ".")
+	cleanZone := strings.TrimSuffix(p.domainName, ".")
+
+	if cleanFQDN == cleanZone {
+		return "@", nil
+	}
+	if !strings.HasSuffix(cleanFQDN, "."+cleanZone) {
+		return "", fmt.Errorf("FQDN %s does not belong to domain %s", fqdn, p.domainName)
+	}
+
+	return strings.TrimSuffix(cleanFQDN, "."+cleanZone), nil
+}
@@ -0,0 +1,163 @@
+package digitalocean
+
+import (
+	"context"
+	"fmt"
+	"net/netip"
+	"testing"
+
+	"github.com/digitalocean/godo"
+)
+
+// fakeDomains is a minimal in-memory stand-in for domainsAPI, keyed by
+// "type/name", used to test UpdateRecord without calling the DigitalOcean
+// API.
+type fakeDomains struct {
+	records map[string][]godo.DomainRecord
+	nextID  int
+}
+
+func newFakeDomains() *fakeDomains {
+	return &fakeDomains{records: map[string][]godo.DomainRecord{}}
+}
+
+func (f *fakeDomains) RecordsByTypeAndName(_ context.Context, _, recordType, name string, _ *godo.ListOptions) ([]godo.DomainRecord, *godo.Response, error) {
+	return f.records[recordType+"/"+name], &godo.Response{}, nil
+}
+
+func (f *fakeDomains) EditRecord(_ context.Context, _ string, id int, req *godo.DomainRecordEditRequest) (*godo.DomainRecord, *godo.Response, error) {
+	key := req.Type + "/" + req.Name
+	for i, rec := range f.records[key] {
+		if rec.ID == id {
+			f.records[key][i].Data = req.Data
+			return &f.records[key][i], &godo.Response{}, nil
+		}
+	}
+	return nil, nil, fmt.Errorf("record %d not found", id)
+}
+
+func (f *fakeDomains) CreateRecord(_ context.Context, _ string, req *godo.DomainRecordEditRequest) (*godo.DomainRecord, *godo.Response, error) {
+	f.nextID++
+	rec := godo.DomainRecord{ID: f.nextID, Type: req.Type, Name: req.Name, Data: req.Data, TTL: req.TTL}
+	key := req.Type + "/" + req.Name
+	f.records[key] = append(f.records[key], rec)
+	return &rec, &godo.Response{}, nil
+}
+
+func (f *fakeDomains) DeleteRecord(_ context.Context, _ string, id int) (*godo.Response, error) {
+	for key, recs := range f.records {
+		for i, rec := range recs {
+			if rec.ID == id {
+				f.records[key] = append(recs[:i], recs[i+1:]...)
+				return &godo.Response{}, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("record %d not found", id)
+}
+
+func dataOf(recs []godo.DomainRecord) []string {
+	data := make([]string, len(recs))
+	for i, r := range recs {
+		data[i] = r.Data
+	}
+	return data
+}
+
+func TestUpdateRecord_ValidationErrors(t *testing.T) {
+	p := &Provider{domains: newFakeDomains(), domainName: "example.com"}
+
+	if err := p.UpdateRecord("", []netip.Addr{netip.MustParseAddr("1.2.3.4")}); err == nil {
+		t.Fatal("expected error for empty fqdn")
+	}
+	if err := p.UpdateRecord("home.example.com", nil); err == nil {
+		t.Fatal("expected error for empty addrs")
+	}
+	if err := p.UpdateRecord("home.other.com", []netip.Addr{netip.MustParseAddr("1.2.3.4")}); err == nil {
+		t.Fatal("expected error for fqdn outside the configured domain")
+	}
+}
+
+func TestUpdateRecord_DualStack(t *testing.T) {
+	fake := newFakeDomains()
+	p := &Provider{domains: fake, domainName: "example.com"}
+
+	err := p.UpdateRecord("home.example.com", []netip.Addr{
+		netip.MustParseAddr("1.2.3.4"),
+		netip.MustParseAddr("2001:db8::1"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	a := fake.records["A/home"]
+	if len(a) != 1 || a[0].Data != "1.2.3.4" {
+		t.Errorf("expected a single A record 1.2.3.4, got %+v", a)
+	}
+	aaaa := fake.records["AAAA/home"]
+	if len(aaaa) != 1 || aaaa[0].Data != "2001:db8::1" {
+		t.Errorf("expected a single AAAA record 2001:db8::1, got %+v", aaaa)
+	}
+}
+
+func TestUpdateRecord_ApexRecord(t *testing.T) {
+	fake := newFakeDomains()
+	p := &Provider{domains: fake, domainName: "example.com"}
+
+	if err := p.UpdateRecord("example.com", []netip.Addr{netip.MustParseAddr("1.2.3.4")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if recs := fake.records["A/@"]; len(recs) != 1 {
+		t.Errorf("expected the apex record to be stored under name \"@\", got %+v", recs)
+	}
+}
+
+func TestUpdateRecord_UpdatesExistingRecord(t *testing.T) {
+	fake := newFakeDomains()
+	fake.records["A/home"] = []godo.DomainRecord{{ID: 7, Type: "A", Name: "home", Data: "1.1.1.1"}}
+	p := &Provider{domains: fake, domainName: "example.com"}
+
+	if err := p.UpdateRecord("home.example.com", []netip.Addr{netip.MustParseAddr("2.2.2.2")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	recs := fake.records["A/home"]
+	if len(recs) != 1 || recs[0].ID != 7 {
+		t.Errorf("expected the existing record to be updated in place, got %+v", recs)
+	}
+	if recs[0].Data != "2.2.2.2" {
+		t.Errorf("expected data 2.2.2.2, got %q", recs[0].Data)
+	}
+}
+
+func TestUpdateRecord_MultipleAddressesPerFamily(t *testing.T) {
+	fake := newFakeDomains()
+	fake.records["A/home"] = []godo.DomainRecord{{ID: 7, Type: "A", Name: "home", Data: "9.9.9.9"}}
+	p := &Provider{domains: fake, domainName: "example.com"}
+
+	err := p.UpdateRecord("home.example.com", []netip.Addr{
+		netip.MustParseAddr("1.2.3.4"),
+		netip.MustParseAddr("1.2.3.5"),
+		netip.MustParseAddr("1.2.3.6"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	got := dataOf(fake.records["A/home"])
+	if len(got) != 3 {
+		t.Fatalf("expected all 3 addresses to be present as separate records, got %v", got)
+	}
+	if fake.records["A/home"][0].ID != 7 {
+		t.Errorf("expected the first address to reuse the existing record, got %+v", fake.records["A/home"][0])
+	}
+
+	// A subsequent update with fewer addresses should delete the extras
+	// rather than leaving stale records behind.
+	if err := p.UpdateRecord("home.example.com", []netip.Addr{netip.MustParseAddr("1.2.3.4")}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if recs := fake.records["A/home"]; len(recs) != 1 {
+		t.Errorf("expected stale records to be deleted, got %+v", recs)
+	}
+}
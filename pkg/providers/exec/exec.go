@@ -0,0 +1,132 @@
+// Package exec implements a generic provider.Provider that shells out to an
+// external program for every update, following lego's exec DNS provider
+// pattern. This lets operators integrate arbitrary DNS backends (RFC 2136
+// nsupdate, a PowerDNS API, a home-grown system) without writing Go code.
+package exec
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/netip"
+	"os"
+	"os/exec"
+	"time"
+
+	"github.com/cldmnky/dyndnsr53/pkg/provider"
+)
+
+func init() {
+	provider.DefaultRegistry.Register("exec", func(ctx context.Context) (provider.Provider, error) {
+		return NewProviderFromEnv()
+	})
+}
+
+// Provider implements the Provider, ContextualProvider, and TXTProvider
+// interfaces.
+var (
+	_ provider.Provider           = (*Provider)(nil)
+	_ provider.ContextualProvider = (*Provider)(nil)
+	_ provider.TXTProvider        = (*Provider)(nil)
+)
+
+// defaultTimeout bounds how long the external program is given to run
+// before it is killed.
+const defaultTimeout = 30 * time.Second
+
+// Provider invokes an external program to perform DNS updates.
+type Provider struct {
+	path    string
+	timeout time.Duration
+}
+
+// NewProvider creates a Provider that invokes the program at path, killing
+// it if a single invocation runs longer than timeout.
+func NewProvider(path string, timeout time.Duration) (*Provider, error) {
+	if path == "" {
+		return nil, fmt.Errorf("path must not be empty")
+	}
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &Provider{path: path, timeout: timeout}, nil
+}
+
+// NewProviderFromEnv builds a Provider from the EXEC_PATH and
+// EXEC_TIMEOUT environment variables, the way lego's exec DNS provider is
+// configured.
+func NewProviderFromEnv() (*Provider, error) {
+	timeout := defaultTimeout
+	if raw := os.Getenv("EXEC_TIMEOUT"); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid EXEC_TIMEOUT %q: %w", raw, err)
+		}
+		timeout = parsed
+	}
+	return NewProvider(os.Getenv("EXEC_PATH"), timeout)
+}
+
+// UpdateRecord implements provider.Provider by invoking the configured
+// program once per address, as "<path> present <fqdn> <addr>".
+func (p *Provider) UpdateRecord(fqdn string, addrs []netip.Addr) error {
+	return p.UpdateRecordWithContext(fqdn, addrs, provider.RequestContext{})
+}
+
+// UpdateRecordWithContext implements provider.ContextualProvider, threading
+// reqCtx through to the program as the DYNDNSR53_REMOTE/DYNDNSR53_USER
+// environment variables.
+func (p *Provider) UpdateRecordWithContext(fqdn string, addrs []netip.Addr, reqCtx provider.RequestContext) error {
+	if fqdn == "" || len(addrs) == 0 {
+		return fmt.Errorf("fqdn and addrs must not be empty")
+	}
+	for _, addr := range addrs {
+		if err := p.run(reqCtx, "present", fqdn, addr.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// PresentTXT implements provider.TXTProvider by invoking the program as
+// "<path> present <fqdn> <value>", the same "present" verb UpdateRecord
+// uses for A/AAAA records.
+func (p *Provider) PresentTXT(fqdn, value string) error {
+	return p.run(provider.RequestContext{}, "present", fqdn, value)
+}
+
+// CleanupTXT implements provider.TXTProvider by invoking the program as
+// "<path> cleanup <fqdn> <value>".
+func (p *Provider) CleanupTXT(fqdn, value string) error {
+	return p.run(provider.RequestContext{}, "cleanup", fqdn, value)
+}
+
+// run executes the configured program as "<path> <action> <fqdn> <value>",
+// with reqCtx passed through as environment variables. The program's
+// combined stdout/stderr is appended to reqCtx.Output, if set, whether the
+// run succeeds or fails; a non-zero exit additionally returns an error that
+// includes the output, so callers can surface it as server.MsgDNSErr even
+// when they don't inspect reqCtx.Output.
+func (p *Provider) run(reqCtx provider.RequestContext, action, fqdn, value string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, p.path, action, fqdn, value)
+	cmd.Env = append(os.Environ(),
+		"DYNDNSR53_REMOTE="+reqCtx.Remote,
+		"DYNDNSR53_USER="+reqCtx.User,
+	)
+
+	var output bytes.Buffer
+	cmd.Stdout = &output
+	cmd.Stderr = &output
+
+	err := cmd.Run()
+	if reqCtx.Output != nil {
+		reqCtx.Output.WriteString(output.String())
+	}
+	if err != nil {
+		return fmt.Errorf("%s %s %s: %w: %s", p.path, action, fqdn, err, output.String())
+	}
+	return nil
+}
@@ -0,0 +1,137 @@
+package exec
+
+import (
+	"fmt"
+	"net/netip"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/cldmnky/dyndnsr53/pkg/provider"
+)
+
+// writeScript writes an executable shell script to a temp file that records
+// its arguments and the DYNDNSR53_* environment variables it was invoked
+// with into a log file, then exits with exitCode.
+func writeScript(t *testing.T, logPath string, exitCode int) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("test script is a POSIX shell script")
+	}
+
+	scriptPath := filepath.Join(t.TempDir(), "fake-update.sh")
+	script := fmt.Sprintf(`#!/bin/sh
+echo "args:$@ remote:$DYNDNSR53_REMOTE user:$DYNDNSR53_USER" >> %q
+exit %d
+`, logPath, exitCode)
+	if err := os.WriteFile(scriptPath, []byte(script), 0o755); err != nil {
+		t.Fatalf("failed to write test script: %v", err)
+	}
+	return scriptPath
+}
+
+func TestUpdateRecordWithContext_Success(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "calls.log")
+	scriptPath := writeScript(t, logPath, 0)
+
+	p, err := NewProvider(scriptPath, time.Second)
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+
+	addrs := []netip.Addr{netip.MustParseAddr("1.2.3.4"), netip.MustParseAddr("2001:db8::1")}
+	err = p.UpdateRecordWithContext("home.example.com", addrs, provider.RequestContext{Remote: "10.0.0.1", User: "alice"})
+	if err != nil {
+		t.Fatalf("UpdateRecordWithContext() error = %v", err)
+	}
+
+	logData, err := os.ReadFile(logPath)
+	if err != nil {
+		t.Fatalf("failed to read log: %v", err)
+	}
+	log := string(logData)
+	for _, want := range []string{
+		"args:present home.example.com 1.2.3.4",
+		"args:present home.example.com 2001:db8::1",
+		"remote:10.0.0.1",
+		"user:alice",
+	} {
+		if !strings.Contains(log, want) {
+			t.Errorf("expected log to contain %q, got %q", want, log)
+		}
+	}
+}
+
+func TestUpdateRecord_NonZeroExit(t *testing.T) {
+	scriptPath := writeScript(t, filepath.Join(t.TempDir(), "calls.log"), 1)
+
+	p, err := NewProvider(scriptPath, time.Second)
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+
+	err = p.UpdateRecord("home.example.com", []netip.Addr{netip.MustParseAddr("1.2.3.4")})
+	if err == nil {
+		t.Fatal("expected an error for a non-zero exit code")
+	}
+}
+
+func TestUpdateRecordWithContext_CapturesOutputOnSuccess(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("test script is a POSIX shell script")
+	}
+	scriptPath := filepath.Join(t.TempDir(), "echo-stdout.sh")
+	if err := os.WriteFile(scriptPath, []byte("#!/bin/sh\necho \"updated $2 to $3\"\n"), 0o755); err != nil {
+		t.Fatalf("failed to write test script: %v", err)
+	}
+
+	p, err := NewProvider(scriptPath, time.Second)
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+
+	var output strings.Builder
+	reqCtx := provider.RequestContext{Remote: "10.0.0.1", User: "alice", Output: &output}
+	if err := p.UpdateRecordWithContext("home.example.com", []netip.Addr{netip.MustParseAddr("1.2.3.4")}, reqCtx); err != nil {
+		t.Fatalf("UpdateRecordWithContext() error = %v", err)
+	}
+
+	if !strings.Contains(output.String(), "updated home.example.com to 1.2.3.4") {
+		t.Errorf("expected reqCtx.Output to capture the script's stdout on success, got %q", output.String())
+	}
+}
+
+func TestPresentCleanupTXT(t *testing.T) {
+	logPath := filepath.Join(t.TempDir(), "calls.log")
+	scriptPath := writeScript(t, logPath, 0)
+
+	p, err := NewProvider(scriptPath, time.Second)
+	if err != nil {
+		t.Fatalf("NewProvider() error = %v", err)
+	}
+
+	if err := p.PresentTXT("_acme-challenge.example.com.", "token-value"); err != nil {
+		t.Fatalf("PresentTXT() error = %v", err)
+	}
+	if err := p.CleanupTXT("_acme-challenge.example.com.", "token-value"); err != nil {
+		t.Fatalf("CleanupTXT() error = %v", err)
+	}
+
+	logData, _ := os.ReadFile(logPath)
+	log := string(logData)
+	if !strings.Contains(log, "args:present _acme-challenge.example.com. token-value") {
+		t.Errorf("expected a present call, got %q", log)
+	}
+	if !strings.Contains(log, "args:cleanup _acme-challenge.example.com. token-value") {
+		t.Errorf("expected a cleanup call, got %q", log)
+	}
+}
+
+func TestNewProvider_EmptyPath(t *testing.T) {
+	if _, err := NewProvider("", time.Second); err == nil {
+		t.Fatal("expected an error for an empty path")
+	}
+}
@@ -3,7 +3,11 @@ package route53
 import (
 	"context"
 	"fmt"
+	"net/netip"
+	"os"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/config"
@@ -13,92 +17,365 @@ import (
 	"github.com/cldmnky/dyndnsr53/pkg/provider"
 )
 
+func init() {
+	provider.DefaultRegistry.Register("route53", func(ctx context.Context) (provider.Provider, error) {
+		return NewProviderFromEnv(ctx)
+	})
+}
+
 // Route53Provider implements the Provider interface for AWS Route53
 var _ provider.Provider = (*Provider)(nil)
 
-// Provider represents a Route53 DNS provider
+// Provider also implements provider.TXTProvider for ACME DNS-01 challenges.
+var _ provider.TXTProvider = (*Provider)(nil)
+
+// recordTTL is the TTL set on upserted records, and also how long a
+// resolved fqdn->zoneID mapping is cached for.
+const recordTTL = 60 * time.Second
+
+// route53API is the subset of the Route53 client Provider depends on, so
+// tests can substitute a fake implementation instead of hitting AWS.
+type route53API interface {
+	GetHostedZone(ctx context.Context, params *route53.GetHostedZoneInput, optFns ...func(*route53.Options)) (*route53.GetHostedZoneOutput, error)
+	ListHostedZonesByName(ctx context.Context, params *route53.ListHostedZonesByNameInput, optFns ...func(*route53.Options)) (*route53.ListHostedZonesByNameOutput, error)
+	ListResourceRecordSets(ctx context.Context, params *route53.ListResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ListResourceRecordSetsOutput, error)
+	ChangeResourceRecordSets(ctx context.Context, params *route53.ChangeResourceRecordSetsInput, optFns ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error)
+}
+
+// zoneCacheEntry is a cached fqdn->zoneID resolution.
+type zoneCacheEntry struct {
+	zoneID    string
+	expiresAt time.Time
+}
+
+// Provider represents a Route53 DNS provider. It discovers the
+// authoritative hosted zone for each FQDN on demand, using an optional
+// pre-configured hosted zone only when discovery finds none.
 type Provider struct {
-	client   *route53.Client
+	client route53API
+
+	// zoneID and zoneName are an optional fallback, used when zone
+	// discovery can't find an authoritative hosted zone for an FQDN.
 	zoneID   string
-	zoneName string // The domain name of the hosted zone (e.g., "blahonga.me")
+	zoneName string
+
+	cacheMu sync.Mutex
+	cache   map[string]zoneCacheEntry
 }
 
-// NewRoute53Provider creates a new Route53Provider for a specific hosted zone
+// NewRoute53Provider creates a new Route53Provider. zoneID is optional: when
+// set, it is used as a fallback hosted zone if automatic discovery (see
+// UpdateRecord) can't resolve one for a given FQDN.
 func NewRoute53Provider(ctx context.Context, zoneID string, awsCfg aws.Config) (*Provider, error) {
-	if zoneID == "" {
-		return nil, fmt.Errorf("zoneID must not be empty")
-	}
 	client := route53.NewFromConfig(awsCfg)
 
-	// Get the hosted zone to retrieve the zone name
-	zoneResp, err := client.GetHostedZone(ctx, &route53.GetHostedZoneInput{
-		Id: aws.String(zoneID),
-	})
+	p := &Provider{
+		client: client,
+		cache:  make(map[string]zoneCacheEntry),
+	}
+
+	if zoneID != "" {
+		// Get the hosted zone to retrieve the zone name
+		zoneResp, err := client.GetHostedZone(ctx, &route53.GetHostedZoneInput{
+			Id: aws.String(zoneID),
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to get hosted zone %s: %w", zoneID, err)
+		}
+		p.zoneID = zoneID
+		p.zoneName = strings.TrimSuffix(*zoneResp.HostedZone.Name, ".")
+	}
+
+	return p, nil
+}
+
+// UpdateRecord upserts the A and/or AAAA records for the given FQDN to
+// addrs, grouping addresses by family into a single Change per record type
+// so that both families are applied in one ChangeBatch.
+func (p *Provider) UpdateRecord(fqdn string, addrs []netip.Addr) error {
+	if fqdn == "" || len(addrs) == 0 {
+		return fmt.Errorf("fqdn and addrs must not be empty")
+	}
+
+	ctx := context.Background()
+	zoneID, err := p.resolveZoneID(ctx, fqdn)
+	if err != nil {
+		return err
+	}
+
+	changes, err := buildChanges(fqdn, addrs)
 	if err != nil {
-		return nil, fmt.Errorf("failed to get hosted zone %s: %w", zoneID, err)
+		return err
+	}
+
+	input := &route53.ChangeResourceRecordSetsInput{
+		HostedZoneId: aws.String(zoneID),
+		ChangeBatch:  &types.ChangeBatch{Changes: changes},
+	}
+	_, err = p.client.ChangeResourceRecordSets(ctx, input)
+	return err
+}
+
+// buildChanges groups addrs by record type (A for IPv4, AAAA for IPv6) and
+// returns one upsert Change per type present.
+func buildChanges(fqdn string, addrs []netip.Addr) ([]types.Change, error) {
+	var v4, v6 []types.ResourceRecord
+	for _, addr := range addrs {
+		rr := types.ResourceRecord{Value: aws.String(addr.String())}
+		if addr.Is4() || addr.Is4In6() {
+			v4 = append(v4, rr)
+		} else {
+			v6 = append(v6, rr)
+		}
+	}
+
+	var changes []types.Change
+	if len(v4) > 0 {
+		changes = append(changes, upsertChange(fqdn, types.RRTypeA, v4))
+	}
+	if len(v6) > 0 {
+		changes = append(changes, upsertChange(fqdn, types.RRTypeAaaa, v6))
 	}
+	if len(changes) == 0 {
+		return nil, fmt.Errorf("no valid addresses to update for %s", fqdn)
+	}
+	return changes, nil
+}
+
+// upsertChange builds a single UPSERT Change for the given record type.
+func upsertChange(fqdn string, rrType types.RRType, records []types.ResourceRecord) types.Change {
+	return types.Change{
+		Action: types.ChangeActionUpsert,
+		ResourceRecordSet: &types.ResourceRecordSet{
+			Name:            aws.String(fqdn),
+			Type:            rrType,
+			TTL:             aws.Int64(int64(recordTTL.Seconds())),
+			ResourceRecords: records,
+		},
+	}
+}
 
-	zoneName := strings.TrimSuffix(*zoneResp.HostedZone.Name, ".")
+// PresentTXT upserts an ACME DNS-01 TXT record for fqdn, merging value into
+// any TXT values already present on the record. Route53 requires the full
+// merged value list on every UPSERT, so this reads the existing record
+// first.
+func (p *Provider) PresentTXT(fqdn, value string) error {
+	return p.updateTXT(fqdn, func(values []string) []string {
+		for _, v := range values {
+			if v == value {
+				return values
+			}
+		}
+		return append(values, value)
+	})
+}
 
-	return &Provider{
-		client:   client,
-		zoneID:   zoneID,
-		zoneName: zoneName,
-	}, nil
+// CleanupTXT removes value from the TXT record for fqdn, deleting the
+// record entirely once no values remain.
+func (p *Provider) CleanupTXT(fqdn, value string) error {
+	return p.updateTXT(fqdn, func(values []string) []string {
+		remaining := make([]string, 0, len(values))
+		for _, v := range values {
+			if v != value {
+				remaining = append(remaining, v)
+			}
+		}
+		return remaining
+	})
 }
 
-// UpdateRecord updates the A record for the given FQDN to the specified IP address
-func (p *Provider) UpdateRecord(fqdn, ip string) error {
-	if fqdn == "" || ip == "" {
-		return fmt.Errorf("fqdn and ip must not be empty")
+// updateTXT reads the current TXT record set for fqdn (if any), applies
+// mutate to its values, and writes the result back as a single UPSERT, or a
+// DELETE if mutate leaves no values behind.
+func (p *Provider) updateTXT(fqdn string, mutate func([]string) []string) error {
+	if fqdn == "" {
+		return fmt.Errorf("fqdn must not be empty")
 	}
 
-	// Validate that the FQDN belongs to our hosted zone
-	if err := p.validateFQDN(fqdn); err != nil {
+	ctx := context.Background()
+	zoneID, err := p.resolveZoneID(ctx, fqdn)
+	if err != nil {
 		return err
 	}
 
+	existing, err := p.listTXTValues(ctx, zoneID, fqdn)
+	if err != nil {
+		return err
+	}
+
+	values := mutate(existing)
+	if len(values) == 0 && len(existing) == 0 {
+		return nil
+	}
+
+	action := types.ChangeActionUpsert
+	recordSetValues := values
+	if len(values) == 0 {
+		action = types.ChangeActionDelete
+		recordSetValues = existing
+	}
+
 	input := &route53.ChangeResourceRecordSetsInput{
-		HostedZoneId: aws.String(p.zoneID),
+		HostedZoneId: aws.String(zoneID),
 		ChangeBatch: &types.ChangeBatch{
 			Changes: []types.Change{
 				{
-					Action: types.ChangeActionUpsert,
-					ResourceRecordSet: &types.ResourceRecordSet{
-						Name:            aws.String(fqdn),
-						Type:            types.RRTypeA,
-						TTL:             aws.Int64(60),
-						ResourceRecords: []types.ResourceRecord{{Value: aws.String(ip)}},
-					},
+					Action:            action,
+					ResourceRecordSet: txtRecordSet(fqdn, recordSetValues),
 				},
 			},
 		},
 	}
-	_, err := p.client.ChangeResourceRecordSets(context.Background(), input)
+	_, err = p.client.ChangeResourceRecordSets(ctx, input)
 	return err
 }
 
-// validateFQDN ensures the FQDN belongs to the configured hosted zone
-func (p *Provider) validateFQDN(fqdn string) error {
-	// Remove trailing dot if present
+// listTXTValues returns the unquoted values of the existing TXT record set
+// for fqdn, or nil if it doesn't exist.
+func (p *Provider) listTXTValues(ctx context.Context, zoneID, fqdn string) ([]string, error) {
 	cleanFQDN := strings.TrimSuffix(fqdn, ".")
-	cleanZone := strings.TrimSuffix(p.zoneName, ".")
 
-	// Check if FQDN ends with our zone name
-	if !strings.HasSuffix(cleanFQDN, "."+cleanZone) && cleanFQDN != cleanZone {
-		return fmt.Errorf("FQDN %s does not belong to hosted zone %s", fqdn, p.zoneName)
+	resp, err := p.client.ListResourceRecordSets(ctx, &route53.ListResourceRecordSetsInput{
+		HostedZoneId:    aws.String(zoneID),
+		StartRecordName: aws.String(fqdn),
+		StartRecordType: types.RRTypeTxt,
+		MaxItems:        aws.Int32(1),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list resource record sets for %s: %w", fqdn, err)
+	}
+
+	for _, rrset := range resp.ResourceRecordSets {
+		if rrset.Type != types.RRTypeTxt || strings.TrimSuffix(aws.ToString(rrset.Name), ".") != cleanFQDN {
+			continue
+		}
+		values := make([]string, 0, len(rrset.ResourceRecords))
+		for _, rr := range rrset.ResourceRecords {
+			values = append(values, unquoteTXT(aws.ToString(rr.Value)))
+		}
+		return values, nil
+	}
+
+	return nil, nil
+}
+
+// txtRecordSet builds the ResourceRecordSet for a TXT record, quoting each
+// value as Route53 requires.
+func txtRecordSet(fqdn string, values []string) *types.ResourceRecordSet {
+	records := make([]types.ResourceRecord, len(values))
+	for i, v := range values {
+		records[i] = types.ResourceRecord{Value: aws.String(quoteTXT(v))}
+	}
+	return &types.ResourceRecordSet{
+		Name:            aws.String(fqdn),
+		Type:            types.RRTypeTxt,
+		TTL:             aws.Int64(int64(recordTTL.Seconds())),
+		ResourceRecords: records,
 	}
+}
 
-	// Additional check: ensure the FQDN doesn't contain the zone name multiple times
-	// This prevents cases like "home.blahonga.me.blahonga.me"
-	if strings.Count(cleanFQDN, cleanZone) > 1 {
-		return fmt.Errorf("FQDN %s contains zone name %s multiple times", fqdn, p.zoneName)
+// quoteTXT wraps v in double quotes as Route53 expects for TXT record
+// values, unless it is already quoted.
+func quoteTXT(v string) string {
+	if strings.HasPrefix(v, `"`) && strings.HasSuffix(v, `"`) {
+		return v
 	}
+	return `"` + v + `"`
+}
 
-	return nil
+// unquoteTXT strips the surrounding double quotes Route53 returns on TXT
+// record values.
+func unquoteTXT(v string) string {
+	return strings.Trim(v, `"`)
+}
+
+// resolveZoneID finds the hosted zone authoritative for fqdn by walking up
+// its labels from most to least specific and querying Route53 for the
+// longest matching zone name - the same suffix-walking pattern lego uses to
+// find the authoritative zone for its easydns provider, rather than trusting
+// a single preconfigured zoneID. Resolutions are cached for recordTTL, and
+// discovery falls back to the provider's configured zoneID, if any.
+func (p *Provider) resolveZoneID(ctx context.Context, fqdn string) (string, error) {
+	cleanFQDN := strings.TrimSuffix(fqdn, ".")
+
+	if zoneID, ok := p.cacheLookup(cleanFQDN); ok {
+		return zoneID, nil
+	}
+
+	labels := strings.Split(cleanFQDN, ".")
+	for i := 0; i <= len(labels)-2; i++ {
+		candidate := strings.Join(labels[i:], ".")
+		zoneID, err := p.findHostedZone(ctx, candidate)
+		if err != nil {
+			return "", err
+		}
+		if zoneID != "" {
+			p.cacheStore(cleanFQDN, zoneID)
+			return zoneID, nil
+		}
+	}
+
+	if p.zoneID != "" {
+		p.cacheStore(cleanFQDN, p.zoneID)
+		return p.zoneID, nil
+	}
+
+	return "", fmt.Errorf("no hosted zone found for FQDN %s", fqdn)
+}
+
+// findHostedZone looks up the hosted zone exactly matching name, returning
+// "" if no such zone exists.
+func (p *Provider) findHostedZone(ctx context.Context, name string) (string, error) {
+	resp, err := p.client.ListHostedZonesByName(ctx, &route53.ListHostedZonesByNameInput{
+		DNSName: aws.String(name),
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to list hosted zones for %s: %w", name, err)
+	}
+
+	for _, zone := range resp.HostedZones {
+		if strings.TrimSuffix(aws.ToString(zone.Name), ".") == name {
+			return strings.TrimPrefix(aws.ToString(zone.Id), "/hostedzone/"), nil
+		}
+	}
+
+	return "", nil
+}
+
+func (p *Provider) cacheLookup(fqdn string) (string, bool) {
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+
+	entry, ok := p.cache[fqdn]
+	if !ok || time.Now().After(entry.expiresAt) {
+		return "", false
+	}
+	return entry.zoneID, true
+}
+
+func (p *Provider) cacheStore(fqdn, zoneID string) {
+	p.cacheMu.Lock()
+	defer p.cacheMu.Unlock()
+
+	p.cache[fqdn] = zoneCacheEntry{
+		zoneID:    zoneID,
+		expiresAt: time.Now().Add(recordTTL),
+	}
 }
 
 // Helper to load AWS config (optional, for convenience)
 func LoadAWSConfig(ctx context.Context) (aws.Config, error) {
 	return config.LoadDefaultConfig(ctx)
 }
+
+// NewProviderFromEnv builds a Provider using the standard AWS SDK credential
+// chain plus the optional AWS_HOSTED_ZONE_ID environment variable as a
+// fallback zone, the way lego's route53 DNS challenge provider is
+// configured from the environment.
+func NewProviderFromEnv(ctx context.Context) (*Provider, error) {
+	cfg, err := LoadAWSConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load AWS config: %w", err)
+	}
+	return NewRoute53Provider(ctx, os.Getenv("AWS_HOSTED_ZONE_ID"), cfg)
+}
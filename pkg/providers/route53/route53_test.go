@@ -2,104 +2,276 @@ package route53
 
 import (
 	"context"
+	"net/netip"
+	"strings"
 	"testing"
 
 	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/route53"
+	"github.com/aws/aws-sdk-go-v2/service/route53/types"
 )
 
-func TestNewRoute53Provider(t *testing.T) {
-	// Test with empty zone ID
-	_, err := NewRoute53Provider(context.Background(), "", aws.Config{})
-	if err == nil {
-		t.Fatal("expected error for empty zoneID")
-	}
+// fakeRoute53 is a minimal in-memory stand-in for route53API, keyed by zone
+// name, used to test zone discovery without calling AWS.
+type fakeRoute53 struct {
+	zones         map[string]string // zone name -> zone ID
+	changeBatches []*route53.ChangeResourceRecordSetsInput
+	listCallCount int
 
-	// Note: Testing with valid zone ID would require actual AWS API call,
-	// so we skip that in unit tests. Integration tests should cover this.
+	// txtRecords simulates the TXT record sets currently stored in Route53,
+	// keyed by the clean (no trailing dot) FQDN.
+	txtRecords map[string][]types.ResourceRecord
 }
 
-func TestUpdateRecord_ValidationErrors(t *testing.T) {
-	// Create a provider with a mock zone name for testing
-	provider := &Provider{
-		client:   nil, // We won't call Route53 API in these tests
-		zoneID:   "Z1234567890",
-		zoneName: "blahonga.me",
+func (f *fakeRoute53) GetHostedZone(_ context.Context, params *route53.GetHostedZoneInput, _ ...func(*route53.Options)) (*route53.GetHostedZoneOutput, error) {
+	for name, id := range f.zones {
+		if id == aws.ToString(params.Id) {
+			return &route53.GetHostedZoneOutput{
+				HostedZone: &types.HostedZone{
+					Id:   params.Id,
+					Name: aws.String(name + "."),
+				},
+			}, nil
+		}
 	}
+	return nil, &types.NoSuchHostedZone{}
+}
 
-	// Test with empty FQDN
-	err := provider.UpdateRecord("", "1.2.3.4")
-	if err == nil {
-		t.Fatal("expected error for empty fqdn")
+func (f *fakeRoute53) ListHostedZonesByName(_ context.Context, params *route53.ListHostedZonesByNameInput, _ ...func(*route53.Options)) (*route53.ListHostedZonesByNameOutput, error) {
+	f.listCallCount++
+	name := aws.ToString(params.DNSName)
+	if id, ok := f.zones[name]; ok {
+		return &route53.ListHostedZonesByNameOutput{
+			HostedZones: []types.HostedZone{
+				{Id: aws.String(id), Name: aws.String(name + ".")},
+			},
+		}, nil
 	}
+	return &route53.ListHostedZonesByNameOutput{}, nil
+}
+
+func (f *fakeRoute53) ChangeResourceRecordSets(_ context.Context, params *route53.ChangeResourceRecordSetsInput, _ ...func(*route53.Options)) (*route53.ChangeResourceRecordSetsOutput, error) {
+	f.changeBatches = append(f.changeBatches, params)
 
-	// Test with empty IP
-	err = provider.UpdateRecord("home.blahonga.me", "")
-	if err == nil {
-		t.Fatal("expected error for empty ip")
+	for _, change := range params.ChangeBatch.Changes {
+		rrset := change.ResourceRecordSet
+		if rrset.Type != types.RRTypeTxt {
+			continue
+		}
+		name := strings.TrimSuffix(aws.ToString(rrset.Name), ".")
+		if f.txtRecords == nil {
+			f.txtRecords = make(map[string][]types.ResourceRecord)
+		}
+		switch change.Action {
+		case types.ChangeActionDelete:
+			delete(f.txtRecords, name)
+		default:
+			f.txtRecords[name] = rrset.ResourceRecords
+		}
 	}
 
-	// Test with invalid FQDN (different zone)
-	err = provider.UpdateRecord("home.example.com", "1.2.3.4")
-	if err == nil {
-		t.Fatal("expected error for FQDN not in zone")
+	return &route53.ChangeResourceRecordSetsOutput{}, nil
+}
+
+func (f *fakeRoute53) ListResourceRecordSets(_ context.Context, params *route53.ListResourceRecordSetsInput, _ ...func(*route53.Options)) (*route53.ListResourceRecordSetsOutput, error) {
+	name := strings.TrimSuffix(aws.ToString(params.StartRecordName), ".")
+	records, ok := f.txtRecords[name]
+	if !ok {
+		return &route53.ListResourceRecordSetsOutput{}, nil
+	}
+	return &route53.ListResourceRecordSetsOutput{
+		ResourceRecordSets: []types.ResourceRecordSet{
+			{Name: aws.String(name + "."), Type: types.RRTypeTxt, ResourceRecords: records},
+		},
+	}, nil
+}
+
+func TestNewRoute53Provider(t *testing.T) {
+	// zoneID is now optional - an empty one should succeed and rely on
+	// discovery or an error from UpdateRecord later.
+	p, err := NewRoute53Provider(context.Background(), "", aws.Config{})
+	if err != nil {
+		t.Fatalf("expected no error for empty zoneID, got %v", err)
+	}
+	if p.zoneID != "" {
+		t.Errorf("expected no fallback zoneID, got %q", p.zoneID)
 	}
 }
 
-func TestValidateFQDN(t *testing.T) {
+func TestUpdateRecord_ValidationErrors(t *testing.T) {
 	provider := &Provider{
-		client:   nil,
-		zoneID:   "Z1234567890",
-		zoneName: "blahonga.me",
+		client: &fakeRoute53{zones: map[string]string{"blahonga.me": "Z1234567890"}},
+		cache:  make(map[string]zoneCacheEntry),
+	}
+
+	if err := provider.UpdateRecord("", []netip.Addr{netip.MustParseAddr("1.2.3.4")}); err == nil {
+		t.Fatal("expected error for empty fqdn")
+	}
+	if err := provider.UpdateRecord("home.blahonga.me", nil); err == nil {
+		t.Fatal("expected error for empty addrs")
 	}
+}
 
+func TestUpdateRecord_ZoneDiscovery(t *testing.T) {
 	tests := []struct {
-		name    string
-		fqdn    string
-		wantErr bool
+		name       string
+		zones      map[string]string
+		fallback   string
+		fqdn       string
+		wantZoneID string
+		wantErr    bool
 	}{
 		{
-			name:    "valid subdomain",
-			fqdn:    "home.blahonga.me",
-			wantErr: false,
+			name:       "apex domain",
+			zones:      map[string]string{"example.com": "ZAPEX"},
+			fqdn:       "example.com",
+			wantZoneID: "ZAPEX",
 		},
 		{
-			name:    "valid subdomain with trailing dot",
-			fqdn:    "home.blahonga.me.",
-			wantErr: false,
+			name:       "deep subdomain resolves to apex zone",
+			zones:      map[string]string{"example.com": "ZAPEX"},
+			fqdn:       "api.v1.home.example.com",
+			wantZoneID: "ZAPEX",
 		},
 		{
-			name:    "valid apex domain",
-			fqdn:    "blahonga.me",
-			wantErr: false,
+			name:       "cross-zone routing prefers the most specific zone",
+			zones:      map[string]string{"example.com": "ZAPEX", "foo.example.com": "ZFOO"},
+			fqdn:       "a.foo.example.com",
+			wantZoneID: "ZFOO",
 		},
 		{
-			name:    "valid apex domain with trailing dot",
-			fqdn:    "blahonga.me.",
-			wantErr: false,
+			name:       "sibling subdomain still routes to the apex zone",
+			zones:      map[string]string{"example.com": "ZAPEX", "foo.example.com": "ZFOO"},
+			fqdn:       "a.bar.example.com",
+			wantZoneID: "ZAPEX",
 		},
 		{
-			name:    "invalid domain - different zone",
-			fqdn:    "home.example.com",
-			wantErr: true,
+			name:       "falls back to configured zoneID when discovery fails",
+			zones:      map[string]string{},
+			fallback:   "ZFALLBACK",
+			fqdn:       "home.unrelated.net",
+			wantZoneID: "ZFALLBACK",
 		},
 		{
-			name:    "invalid domain - would create double zone",
-			fqdn:    "home.blahonga.me.blahonga.me",
+			name:    "errors when discovery fails and there is no fallback",
+			zones:   map[string]string{},
+			fqdn:    "home.unrelated.net",
 			wantErr: true,
 		},
-		{
-			name:    "valid deep subdomain",
-			fqdn:    "api.v1.home.blahonga.me",
-			wantErr: false,
-		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
-			err := provider.validateFQDN(tt.fqdn)
+			fake := &fakeRoute53{zones: tt.zones}
+			p := &Provider{
+				client: fake,
+				zoneID: tt.fallback,
+				cache:  make(map[string]zoneCacheEntry),
+			}
+
+			err := p.UpdateRecord(tt.fqdn, []netip.Addr{netip.MustParseAddr("1.2.3.4")})
 			if (err != nil) != tt.wantErr {
-				t.Errorf("validateFQDN() error = %v, wantErr %v", err, tt.wantErr)
+				t.Fatalf("UpdateRecord() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+
+			if len(fake.changeBatches) != 1 {
+				t.Fatalf("expected 1 change batch, got %d", len(fake.changeBatches))
+			}
+			gotZoneID := aws.ToString(fake.changeBatches[0].HostedZoneId)
+			if gotZoneID != tt.wantZoneID {
+				t.Errorf("expected zoneID %q, got %q", tt.wantZoneID, gotZoneID)
+			}
+
+			// A second update for the same fqdn should be served from cache
+			// rather than issuing another ListHostedZonesByName call.
+			callsBefore := fake.listCallCount
+			if err := p.UpdateRecord(tt.fqdn, []netip.Addr{netip.MustParseAddr("1.2.3.5")}); err != nil {
+				t.Fatalf("unexpected error on cached update: %v", err)
+			}
+			if fake.listCallCount != callsBefore {
+				t.Errorf("expected cached resolution to skip ListHostedZonesByName, calls went from %d to %d", callsBefore, fake.listCallCount)
 			}
 		})
 	}
 }
+
+func TestUpdateRecord_DualStack(t *testing.T) {
+	fake := &fakeRoute53{zones: map[string]string{"example.com": "ZAPEX"}}
+	p := &Provider{client: fake, cache: make(map[string]zoneCacheEntry)}
+
+	err := p.UpdateRecord("home.example.com", []netip.Addr{
+		netip.MustParseAddr("1.2.3.4"),
+		netip.MustParseAddr("2001:db8::1"),
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(fake.changeBatches) != 1 {
+		t.Fatalf("expected a single ChangeBatch, got %d", len(fake.changeBatches))
+	}
+	changes := fake.changeBatches[0].ChangeBatch.Changes
+	if len(changes) != 2 {
+		t.Fatalf("expected one Change per record type, got %d", len(changes))
+	}
+
+	var sawA, sawAAAA bool
+	for _, c := range changes {
+		switch c.ResourceRecordSet.Type {
+		case types.RRTypeA:
+			sawA = true
+		case types.RRTypeAaaa:
+			sawAAAA = true
+		}
+	}
+	if !sawA || !sawAAAA {
+		t.Errorf("expected both A and AAAA changes, got %+v", changes)
+	}
+}
+
+func TestPresentCleanupTXT(t *testing.T) {
+	fake := &fakeRoute53{zones: map[string]string{"example.com": "ZAPEX"}}
+	p := &Provider{client: fake, cache: make(map[string]zoneCacheEntry)}
+
+	fqdn := "_acme-challenge.example.com."
+
+	if err := p.PresentTXT(fqdn, "token-a"); err != nil {
+		t.Fatalf("PresentTXT() error = %v", err)
+	}
+	values, err := p.listTXTValues(context.Background(), "ZAPEX", fqdn)
+	if err != nil {
+		t.Fatalf("listTXTValues() error = %v", err)
+	}
+	if len(values) != 1 || values[0] != "token-a" {
+		t.Fatalf("expected [token-a], got %v", values)
+	}
+
+	// Presenting a second value should merge rather than overwrite.
+	if err := p.PresentTXT(fqdn, "token-b"); err != nil {
+		t.Fatalf("PresentTXT() error = %v", err)
+	}
+	values, _ = p.listTXTValues(context.Background(), "ZAPEX", fqdn)
+	if len(values) != 2 {
+		t.Fatalf("expected 2 merged values, got %v", values)
+	}
+
+	// Cleaning up one value should leave the other behind.
+	if err := p.CleanupTXT(fqdn, "token-a"); err != nil {
+		t.Fatalf("CleanupTXT() error = %v", err)
+	}
+	values, _ = p.listTXTValues(context.Background(), "ZAPEX", fqdn)
+	if len(values) != 1 || values[0] != "token-b" {
+		t.Fatalf("expected [token-b], got %v", values)
+	}
+
+	// Cleaning up the last value should delete the record set entirely.
+	if err := p.CleanupTXT(fqdn, "token-b"); err != nil {
+		t.Fatalf("CleanupTXT() error = %v", err)
+	}
+	if len(fake.txtRecords) != 0 {
+		t.Fatalf("expected TXT record set to be deleted, got %v", fake.txtRecords)
+	}
+}
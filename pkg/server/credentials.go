@@ -0,0 +1,124 @@
+package server
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"golang.org/x/crypto/bcrypt"
+	"gopkg.in/yaml.v3"
+)
+
+// User is an authenticated DynDNS client and the hostnames it is permitted
+// to update.
+type User struct {
+	Name      string   `yaml:"name"`
+	Password  string   `yaml:"password_hash"`
+	Hostnames []string `yaml:"hostnames"`
+}
+
+// Allows reports whether hostname is covered by u's allow-list. Entries
+// match exactly, as a "*.suffix" wildcard covering any subdomain of suffix
+// (but not suffix itself), or as the literal "*" matching any hostname.
+func (u User) Allows(hostname string) bool {
+	hostname = strings.ToLower(strings.TrimSuffix(hostname, "."))
+	for _, pattern := range u.Hostnames {
+		switch {
+		case pattern == "*":
+			return true
+		case strings.HasPrefix(pattern, "*."):
+			if strings.HasSuffix(hostname, strings.ToLower(strings.TrimPrefix(pattern, "*"))) {
+				return true
+			}
+		case strings.EqualFold(pattern, hostname):
+			return true
+		}
+	}
+	return false
+}
+
+// CredentialStore authenticates DynDNS clients and reports which hostnames
+// they are permitted to update.
+type CredentialStore interface {
+	// Authenticate verifies username/password, returning the matching User
+	// on success.
+	Authenticate(username, password string) (User, bool)
+}
+
+// credentialFile is the on-disk shape of an --auth-file.
+type credentialFile struct {
+	Users []User `yaml:"users"`
+}
+
+// FileCredentialStore is a CredentialStore backed by a YAML file of users,
+// reloadable at runtime via Reload (e.g. on SIGHUP).
+type FileCredentialStore struct {
+	path string
+
+	mu    sync.RWMutex
+	users map[string]User
+}
+
+// NewFileCredentialStore loads users from the YAML file at path.
+func NewFileCredentialStore(path string) (*FileCredentialStore, error) {
+	s := &FileCredentialStore{path: path}
+	if err := s.Reload(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// NewStaticCredentialStore returns a CredentialStore with a single user
+// permitted to update any hostname. It exists for callers that want a
+// credential store without managing an --auth-file, e.g. the serve
+// command's backward-compatible default.
+func NewStaticCredentialStore(username, password string) (*FileCredentialStore, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return nil, fmt.Errorf("failed to hash password: %w", err)
+	}
+	return &FileCredentialStore{
+		users: map[string]User{
+			username: {Name: username, Password: string(hash), Hostnames: []string{"*"}},
+		},
+	}, nil
+}
+
+// Reload re-reads the credential file from disk, atomically replacing the
+// in-memory user list. Safe to call concurrently with Authenticate.
+func (s *FileCredentialStore) Reload() error {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		return fmt.Errorf("failed to read auth file %s: %w", s.path, err)
+	}
+
+	var file credentialFile
+	if err := yaml.Unmarshal(data, &file); err != nil {
+		return fmt.Errorf("failed to parse auth file %s: %w", s.path, err)
+	}
+
+	users := make(map[string]User, len(file.Users))
+	for _, u := range file.Users {
+		users[u.Name] = u
+	}
+
+	s.mu.Lock()
+	s.users = users
+	s.mu.Unlock()
+	return nil
+}
+
+// Authenticate implements CredentialStore.
+func (s *FileCredentialStore) Authenticate(username, password string) (User, bool) {
+	s.mu.RLock()
+	u, ok := s.users[username]
+	s.mu.RUnlock()
+	if !ok {
+		return User{}, false
+	}
+	if bcrypt.CompareHashAndPassword([]byte(u.Password), []byte(password)) != nil {
+		return User{}, false
+	}
+	return u, true
+}
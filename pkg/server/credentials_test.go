@@ -0,0 +1,126 @@
+package server
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+func writeAuthFile(t *testing.T, contents string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "auth.yaml")
+	if err := os.WriteFile(path, []byte(contents), 0o600); err != nil {
+		t.Fatalf("failed to write auth file: %v", err)
+	}
+	return path
+}
+
+func TestFileCredentialStore_Authenticate(t *testing.T) {
+	hash, err := bcrypt.GenerateFromPassword([]byte("s3cret"), bcrypt.MinCost)
+	if err != nil {
+		t.Fatalf("failed to hash password: %v", err)
+	}
+	path := writeAuthFile(t, `
+users:
+  - name: alice
+    password_hash: "`+string(hash)+`"
+    hostnames:
+      - home.example.com
+      - "*.lab.example.com"
+`)
+
+	store, err := NewFileCredentialStore(path)
+	if err != nil {
+		t.Fatalf("NewFileCredentialStore() error = %v", err)
+	}
+
+	if _, ok := store.Authenticate("alice", "wrong"); ok {
+		t.Error("expected authentication to fail with the wrong password")
+	}
+	if _, ok := store.Authenticate("bob", "s3cret"); ok {
+		t.Error("expected authentication to fail for an unknown user")
+	}
+
+	user, ok := store.Authenticate("alice", "s3cret")
+	if !ok {
+		t.Fatal("expected authentication to succeed")
+	}
+	if !user.Allows("home.example.com") {
+		t.Error("expected exact hostname match to be allowed")
+	}
+	if !user.Allows("box1.lab.example.com") {
+		t.Error("expected wildcard suffix match to be allowed")
+	}
+	if user.Allows("lab.example.com") {
+		t.Error("expected wildcard suffix not to match its own apex")
+	}
+	if user.Allows("other.example.com") {
+		t.Error("expected an unlisted hostname to be disallowed")
+	}
+}
+
+func TestFileCredentialStore_Reload(t *testing.T) {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("pw1"), bcrypt.MinCost)
+	path := writeAuthFile(t, `
+users:
+  - name: alice
+    password_hash: "`+string(hash)+`"
+    hostnames: ["*"]
+`)
+
+	store, err := NewFileCredentialStore(path)
+	if err != nil {
+		t.Fatalf("NewFileCredentialStore() error = %v", err)
+	}
+	if _, ok := store.Authenticate("alice", "pw1"); !ok {
+		t.Fatal("expected initial authentication to succeed")
+	}
+
+	hash2, _ := bcrypt.GenerateFromPassword([]byte("pw2"), bcrypt.MinCost)
+	if err := os.WriteFile(path, []byte(`
+users:
+  - name: alice
+    password_hash: "`+string(hash2)+`"
+    hostnames: ["*"]
+`), 0o600); err != nil {
+		t.Fatalf("failed to rewrite auth file: %v", err)
+	}
+
+	if err := store.Reload(); err != nil {
+		t.Fatalf("Reload() error = %v", err)
+	}
+	if _, ok := store.Authenticate("alice", "pw1"); ok {
+		t.Error("expected the old password to be rejected after reload")
+	}
+	if _, ok := store.Authenticate("alice", "pw2"); !ok {
+		t.Error("expected the new password to be accepted after reload")
+	}
+}
+
+func TestUser_Allows(t *testing.T) {
+	tests := []struct {
+		name      string
+		hostnames []string
+		hostname  string
+		want      bool
+	}{
+		{"wildcard allows anything", []string{"*"}, "anything.example.com", true},
+		{"exact match", []string{"home.example.com"}, "home.example.com", true},
+		{"exact match is case-insensitive", []string{"Home.Example.com"}, "home.example.com", true},
+		{"suffix wildcard matches subdomain", []string{"*.home.example.com"}, "office.home.example.com", true},
+		{"suffix wildcard does not match apex", []string{"*.home.example.com"}, "home.example.com", false},
+		{"suffix wildcard matches regardless of pattern case", []string{"*.Lab.Example.com"}, "box1.lab.example.com", true},
+		{"no match", []string{"home.example.com"}, "other.example.com", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			u := User{Hostnames: tt.hostnames}
+			if got := u.Allows(tt.hostname); got != tt.want {
+				t.Errorf("Allows(%q) = %v, want %v", tt.hostname, got, tt.want)
+			}
+		})
+	}
+}
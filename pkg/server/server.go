@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"log/slog"
 	"net/http"
+	"net/netip"
 	"strings"
 	"time"
 
@@ -41,36 +42,38 @@ type RequestLog struct {
 	StatusCode   int       `json:"status_code"`
 	Response     string    `json:"response"`
 	ErrorMessage string    `json:"error_message,omitempty"`
+	Output       string    `json:"output,omitempty"`
 	Duration     string    `json:"duration"`
 }
 
-// Example: hardcoded credentials and user agent for demonstration
-var (
-	validUser      = "user"
-	validPassword  = "pass"
-	validUserAgent = "dyndnsr53-client"
-)
+// validUserAgent is the client User-Agent the DynDNS protocol requires.
+const validUserAgent = "dyndnsr53-client"
 
 // Server holds the DNS provider and configuration
 type Server struct {
-	provider provider.Provider
+	provider  provider.Provider
+	credStore CredentialStore
 }
 
-// NewServer creates a new server with the given provider
-func NewServer(p provider.Provider) *Server {
-	return &Server{provider: p}
+// NewServer creates a new server with the given provider and credential
+// store.
+func NewServer(p provider.Provider, credStore CredentialStore) *Server {
+	return &Server{provider: p, credStore: credStore}
 }
 
 // StartServer starts the HTTP server with DynDNS-compatible handler (deprecated, use NewServer().StartServer())
 func StartServer(addr string) error {
-	// Create a server with no provider for backward compatibility
-	s := &Server{provider: nil}
+	// Create a server with no provider and no credentials for backward
+	// compatibility; every request will fail authentication.
+	s := &Server{}
 	return s.StartServer(addr)
 }
 
 // StartServerWithProvider starts the HTTP server with the given provider
 func (s *Server) StartServer(addr string) error {
 	http.HandleFunc("/nic/update", s.dynDNSUpdateHandler)
+	http.HandleFunc("/dns-01/present", s.dns01PresentHandler)
+	http.HandleFunc("/dns-01/cleanup", s.dns01CleanupHandler)
 	slog.Info("Starting DynDNS API server", "addr", addr)
 	return http.ListenAndServe(addr, nil)
 }
@@ -122,57 +125,212 @@ func (s *Server) dynDNSUpdateHandler(w http.ResponseWriter, r *http.Request) {
 	}
 
 	// Check Basic Auth
-	auth := r.Header.Get("Authorization")
-	if !strings.HasPrefix(auth, "Basic ") {
-		log.Warn("missing auth header")
-		logAndRespond(http.StatusUnauthorized, MsgBadAuth, "missing authorization header")
-		return
-	}
-	payload, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(auth, "Basic "))
-	if err != nil {
-		log.Warn("bad base64 in auth header")
-		logAndRespond(http.StatusUnauthorized, MsgBadAuth, "invalid base64 in auth header")
-		return
-	}
-	parts := strings.SplitN(string(payload), ":", 2)
-	if len(parts) != 2 || parts[0] != validUser || parts[1] != validPassword {
-		log.Warn("bad credentials", "user", parts[0])
-		logAndRespond(http.StatusUnauthorized, MsgBadAuth, "invalid credentials")
+	user, ok := s.checkBasicAuth(r)
+	if !ok {
+		log.Warn("missing or invalid auth header")
+		logAndRespond(http.StatusUnauthorized, MsgBadAuth, "missing or invalid credentials")
 		return
 	}
 
 	// Set username in log
-	reqLog.Username = parts[0]
+	reqLog.Username = user.Name
 
-	// Parse query params
+	// Parse query params. myip carries the standard DynDNS address list;
+	// myipv6/myip6 are the No-IP/Dyn extensions for IPv6-only clients.
 	fqdn := r.URL.Query().Get("hostname")
-	ip := r.URL.Query().Get("myip")
+	myip := r.URL.Query().Get("myip")
+	myip6 := r.URL.Query().Get("myipv6")
+	if myip6 == "" {
+		myip6 = r.URL.Query().Get("myip6")
+	}
 	reqLog.FQDN = fqdn
-	reqLog.IP = ip
+	reqLog.IP = strings.Join(nonEmpty(myip, myip6), ",")
 
 	if fqdn == "" {
 		log.Warn("missing fqdn")
 		logAndRespond(http.StatusOK, MsgNotFQDN, "missing hostname parameter")
 		return
 	}
-	if ip == "" {
+	if !user.Allows(fqdn) {
+		log.Warn("hostname not permitted for user", "user", user.Name, "hostname", fqdn)
+		logAndRespond(http.StatusOK, MsgNoHost, "hostname not permitted for this user")
+		return
+	}
+	if myip == "" && myip6 == "" {
 		log.Warn("missing ip")
-		logAndRespond(http.StatusOK, MsgDNSErr, "missing myip parameter")
+		logAndRespond(http.StatusOK, MsgDNSErr, "missing myip/myipv6 parameter")
+		return
+	}
+
+	// Addresses are classified by their own family rather than which
+	// parameter they arrived in, so a v6 literal sent as myip still updates
+	// the AAAA record.
+	addrs, err := parseAddrs(myip, myip6)
+	if err != nil {
+		log.Warn("invalid ip", "error", err)
+		logAndRespond(http.StatusOK, MsgDNSErr, err.Error())
 		return
 	}
 
 	// Use the provider to update the DNS record
-	log.Info("update request", "fqdn", fqdn, "ip", ip)
+	log.Info("update request", "fqdn", fqdn, "addrs", addrs)
 	if s.provider != nil {
-		if err := s.provider.UpdateRecord(fqdn, ip); err != nil {
+		output, err := s.updateRecord(fqdn, addrs, r.RemoteAddr, user.Name)
+		reqLog.Output = output
+		if err != nil {
 			log.Error("failed to update DNS record", "error", err)
 			logAndRespond(http.StatusOK, MsgDNSErr, fmt.Sprintf("provider error: %v", err))
 			return
 		}
-		logAndRespond(http.StatusOK, fmt.Sprintf("%s %s", MsgGood, ip), "")
+		logAndRespond(http.StatusOK, fmt.Sprintf("%s %s", MsgGood, joinAddrs(addrs)), "")
 	} else {
 		// Fallback for when no provider is configured
 		log.Warn("no provider configured, returning success without update")
-		logAndRespond(http.StatusOK, fmt.Sprintf("%s %s", MsgGood, ip), "no provider configured")
+		logAndRespond(http.StatusOK, fmt.Sprintf("%s %s", MsgGood, joinAddrs(addrs)), "no provider configured")
+	}
+}
+
+// updateRecord calls the provider's UpdateRecord, passing remote/username
+// through as a provider.RequestContext when the provider implements
+// provider.ContextualProvider (e.g. the exec provider, which forwards them
+// to the external program as environment variables). It returns any output
+// the provider captured via RequestContext.Output (e.g. the exec provider's
+// combined stdout/stderr) alongside the call's error, if any, so the caller
+// can log it regardless of success.
+func (s *Server) updateRecord(fqdn string, addrs []netip.Addr, remote, username string) (string, error) {
+	cp, ok := s.provider.(provider.ContextualProvider)
+	if !ok {
+		return "", s.provider.UpdateRecord(fqdn, addrs)
+	}
+	var output strings.Builder
+	err := cp.UpdateRecordWithContext(fqdn, addrs, provider.RequestContext{Remote: remote, User: username, Output: &output})
+	return output.String(), err
+}
+
+// parseAddrs parses the comma-separated address lists (as used by the
+// DynDNS myip parameter) into a single slice of netip.Addr.
+func parseAddrs(lists ...string) ([]netip.Addr, error) {
+	var addrs []netip.Addr
+	for _, list := range lists {
+		if list == "" {
+			continue
+		}
+		for _, raw := range strings.Split(list, ",") {
+			raw = strings.TrimSpace(raw)
+			if raw == "" {
+				continue
+			}
+			addr, err := netip.ParseAddr(raw)
+			if err != nil {
+				return nil, fmt.Errorf("invalid IP address %q: %w", raw, err)
+			}
+			addrs = append(addrs, addr)
+		}
+	}
+	return addrs, nil
+}
+
+// joinAddrs renders addrs as a comma-separated string for the DynDNS response body.
+func joinAddrs(addrs []netip.Addr) string {
+	parts := make([]string, len(addrs))
+	for i, addr := range addrs {
+		parts[i] = addr.String()
+	}
+	return strings.Join(parts, ",")
+}
+
+// nonEmpty returns the non-empty strings among values, preserving order.
+func nonEmpty(values ...string) []string {
+	var out []string
+	for _, v := range values {
+		if v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// dns01Request is the JSON body accepted by /dns-01/present and
+// /dns-01/cleanup: fqdn is the base domain the challenge is for (not yet
+// prefixed with _acme-challenge), and value is the TXT record value.
+type dns01Request struct {
+	FQDN  string `json:"fqdn"`
+	Value string `json:"value"`
+}
+
+// dns01PresentHandler upserts the ACME DNS-01 challenge TXT record.
+func (s *Server) dns01PresentHandler(w http.ResponseWriter, r *http.Request) {
+	s.dns01Handler(w, r, provider.TXTProvider.PresentTXT)
+}
+
+// dns01CleanupHandler removes the ACME DNS-01 challenge TXT record.
+func (s *Server) dns01CleanupHandler(w http.ResponseWriter, r *http.Request) {
+	s.dns01Handler(w, r, provider.TXTProvider.CleanupTXT)
+}
+
+// dns01Handler implements the shared plumbing for the ACME DNS-01 endpoints:
+// Basic Auth, decoding the request, deriving the _acme-challenge FQDN, and
+// invoking op against the configured provider's TXTProvider capability.
+func (s *Server) dns01Handler(w http.ResponseWriter, r *http.Request, op func(provider.TXTProvider, string, string) error) {
+	log := slog.Default().With("remote", r.RemoteAddr)
+
+	user, ok := s.checkBasicAuth(r)
+	if !ok {
+		log.Warn("missing or invalid auth header")
+		http.Error(w, MsgBadAuth, http.StatusUnauthorized)
+		return
+	}
+	if r.Method != http.MethodPost {
+		log.Warn("bad method", "method", r.Method)
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	var req dns01Request
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.FQDN == "" || req.Value == "" {
+		log.Warn("invalid dns-01 request body")
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	if !user.Allows(req.FQDN) {
+		log.Warn("hostname not permitted for user", "user", user.Name, "hostname", req.FQDN)
+		http.Error(w, MsgNoHost, http.StatusForbidden)
+		return
+	}
+
+	txtProvider, ok := s.provider.(provider.TXTProvider)
+	if !ok {
+		log.Warn("provider does not support DNS-01 challenges")
+		http.Error(w, "provider does not support DNS-01 challenges", http.StatusNotImplemented)
+		return
+	}
+
+	challengeFQDN := "_acme-challenge." + strings.TrimSuffix(req.FQDN, ".") + "."
+	if err := op(txtProvider, challengeFQDN, req.Value); err != nil {
+		log.Error("failed to update TXT record", "error", err)
+		http.Error(w, fmt.Sprintf("provider error: %v", err), http.StatusInternalServerError)
+		return
+	}
+
+	w.WriteHeader(http.StatusOK)
+	fmt.Fprintln(w, MsgGood)
+}
+
+// checkBasicAuth verifies HTTP Basic Auth credentials against s.credStore,
+// returning the authenticated User on success.
+func (s *Server) checkBasicAuth(r *http.Request) (User, bool) {
+	auth := r.Header.Get("Authorization")
+	if !strings.HasPrefix(auth, "Basic ") {
+		return User{}, false
+	}
+	payload, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(auth, "Basic "))
+	if err != nil {
+		return User{}, false
+	}
+	parts := strings.SplitN(string(payload), ":", 2)
+	if len(parts) != 2 || s.credStore == nil {
+		return User{}, false
 	}
+	return s.credStore.Authenticate(parts[0], parts[1])
 }
@@ -1,32 +1,72 @@
 package server
 
 import (
+	"bytes"
 	"encoding/base64"
+	"encoding/json"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/netip"
 	"testing"
+
+	"golang.org/x/crypto/bcrypt"
+
+	"github.com/cldmnky/dyndnsr53/pkg/provider"
+)
+
+// testUsername/testPassword are the credentials newTestServer authorizes.
+const (
+	testUsername = "user"
+	testPassword = "pass"
 )
 
 // MockProvider implements the Provider interface for testing
 type MockProvider struct {
 	updateCalled bool
 	updateError  error
+	updateAddrs  []netip.Addr
 }
 
-func (m *MockProvider) UpdateRecord(_, _ string) error {
+func (m *MockProvider) UpdateRecord(_ string, addrs []netip.Addr) error {
 	m.updateCalled = true
+	m.updateAddrs = addrs
 	return m.updateError
 }
 
+// newTestServer builds a Server whose credential store authenticates
+// testUsername/testPassword and permits any hostname, unless hostnames is
+// given, in which case it restricts the user's allow-list to exactly those
+// entries.
+func newTestServer(p provider.Provider, hostnames ...string) *Server {
+	return NewServer(p, newTestCredStore(testUsername, testPassword, hostnames...))
+}
+
+// newTestCredStore builds a single-user in-memory credential store for
+// tests. An empty hostnames list permits any hostname.
+func newTestCredStore(username, password string, hostnames ...string) *FileCredentialStore {
+	if len(hostnames) == 0 {
+		hostnames = []string{"*"}
+	}
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.MinCost)
+	if err != nil {
+		panic(err)
+	}
+	return &FileCredentialStore{
+		users: map[string]User{
+			username: {Name: username, Password: string(hash), Hostnames: hostnames},
+		},
+	}
+}
+
 func TestDynDNSUpdateHandler_Success(t *testing.T) {
 	mockProvider := &MockProvider{}
-	server := NewServer(mockProvider)
+	server := newTestServer(mockProvider)
 
 	req := httptest.NewRequest("GET", "/nic/update?hostname=test.example.com&myip=1.2.3.4", nil)
 	req.Header.Set("User-Agent", validUserAgent)
-	req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", validUser, validPassword))))
+	req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", testUsername, testPassword))))
 
 	rw := httptest.NewRecorder()
 
@@ -47,7 +87,7 @@ func TestDynDNSUpdateHandler_Success(t *testing.T) {
 
 func TestDynDNSUpdateHandler_BadAuth(t *testing.T) {
 	mockProvider := &MockProvider{}
-	server := NewServer(mockProvider)
+	server := newTestServer(mockProvider)
 
 	req := httptest.NewRequest("GET", "/nic/update?hostname=test.example.com&myip=1.2.3.4", nil)
 	req.Header.Set("User-Agent", validUserAgent)
@@ -72,11 +112,11 @@ func TestDynDNSUpdateHandler_BadAuth(t *testing.T) {
 
 func TestDynDNSUpdateHandler_MissingUserAgent(t *testing.T) {
 	mockProvider := &MockProvider{}
-	server := NewServer(mockProvider)
+	server := newTestServer(mockProvider)
 
 	req := httptest.NewRequest("GET", "/nic/update?hostname=test.example.com&myip=1.2.3.4", nil)
 	// No User-Agent
-	req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", validUser, validPassword))))
+	req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", testUsername, testPassword))))
 
 	rw := httptest.NewRecorder()
 
@@ -90,11 +130,11 @@ func TestDynDNSUpdateHandler_MissingUserAgent(t *testing.T) {
 
 func TestDynDNSUpdateHandler_MissingParams(t *testing.T) {
 	mockProvider := &MockProvider{}
-	server := NewServer(mockProvider)
+	server := newTestServer(mockProvider)
 
 	req := httptest.NewRequest("GET", "/nic/update?myip=1.2.3.4", nil)
 	req.Header.Set("User-Agent", validUserAgent)
-	req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", validUser, validPassword))))
+	req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", testUsername, testPassword))))
 
 	rw := httptest.NewRecorder()
 
@@ -106,3 +146,280 @@ func TestDynDNSUpdateHandler_MissingParams(t *testing.T) {
 		t.Errorf("expected %q, got %q", MsgNotFQDN+"\n", got)
 	}
 }
+
+func TestDynDNSUpdateHandler_MyIPv6Param(t *testing.T) {
+	mockProvider := &MockProvider{}
+	server := newTestServer(mockProvider)
+
+	req := httptest.NewRequest("GET", "/nic/update?hostname=test.example.com&myipv6=2001:db8::1", nil)
+	req.Header.Set("User-Agent", validUserAgent)
+	req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", testUsername, testPassword))))
+
+	rw := httptest.NewRecorder()
+	server.dynDNSUpdateHandler(rw, req)
+
+	resp := rw.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 OK, got %d", resp.StatusCode)
+	}
+	if len(mockProvider.updateAddrs) != 1 || !mockProvider.updateAddrs[0].Is6() {
+		t.Fatalf("expected a single IPv6 address, got %v", mockProvider.updateAddrs)
+	}
+}
+
+func TestDynDNSUpdateHandler_MyIPWithV6Literal(t *testing.T) {
+	// A client that sends a v6 literal as myip (rather than myipv6) should
+	// still be routed to AAAA, since classification is by address family.
+	mockProvider := &MockProvider{}
+	server := newTestServer(mockProvider)
+
+	req := httptest.NewRequest("GET", "/nic/update?hostname=test.example.com&myip=2001:db8::1", nil)
+	req.Header.Set("User-Agent", validUserAgent)
+	req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", testUsername, testPassword))))
+
+	rw := httptest.NewRecorder()
+	server.dynDNSUpdateHandler(rw, req)
+
+	if len(mockProvider.updateAddrs) != 1 || !mockProvider.updateAddrs[0].Is6() {
+		t.Fatalf("expected a single IPv6 address, got %v", mockProvider.updateAddrs)
+	}
+}
+
+func TestDynDNSUpdateHandler_MyIPCommaSeparatedList(t *testing.T) {
+	mockProvider := &MockProvider{}
+	server := newTestServer(mockProvider)
+
+	req := httptest.NewRequest("GET", "/nic/update?hostname=test.example.com&myip=1.2.3.4,2001:db8::1", nil)
+	req.Header.Set("User-Agent", validUserAgent)
+	req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", testUsername, testPassword))))
+
+	rw := httptest.NewRecorder()
+	server.dynDNSUpdateHandler(rw, req)
+
+	if len(mockProvider.updateAddrs) != 2 {
+		t.Fatalf("expected 2 addresses, got %d: %v", len(mockProvider.updateAddrs), mockProvider.updateAddrs)
+	}
+}
+
+func TestDynDNSUpdateHandler_InvalidIP(t *testing.T) {
+	mockProvider := &MockProvider{}
+	server := newTestServer(mockProvider)
+
+	req := httptest.NewRequest("GET", "/nic/update?hostname=test.example.com&myip=not-an-ip", nil)
+	req.Header.Set("User-Agent", validUserAgent)
+	req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", testUsername, testPassword))))
+
+	rw := httptest.NewRecorder()
+	server.dynDNSUpdateHandler(rw, req)
+
+	resp := rw.Result()
+	body, _ := io.ReadAll(resp.Body)
+	if got := string(body); got != MsgDNSErr+"\n" {
+		t.Errorf("expected %q, got %q", MsgDNSErr+"\n", got)
+	}
+	if mockProvider.updateCalled {
+		t.Error("expected provider.UpdateRecord not to be called for an invalid IP")
+	}
+}
+
+// MockContextualProvider implements provider.ContextualProvider, writing a
+// fixed string into the RequestContext.Output it's given, the way the exec
+// provider writes the external program's captured stdout/stderr.
+type MockContextualProvider struct {
+	MockProvider
+}
+
+func (m *MockContextualProvider) UpdateRecordWithContext(_ string, addrs []netip.Addr, reqCtx provider.RequestContext) error {
+	m.updateCalled = true
+	m.updateAddrs = addrs
+	if reqCtx.Output != nil {
+		reqCtx.Output.WriteString("mock output")
+	}
+	return m.updateError
+}
+
+func TestUpdateRecord_ReturnsContextualProviderOutput(t *testing.T) {
+	mockProvider := &MockContextualProvider{}
+	server := newTestServer(mockProvider)
+
+	output, err := server.updateRecord("home.example.com", []netip.Addr{netip.MustParseAddr("1.2.3.4")}, "10.0.0.1", "alice")
+	if err != nil {
+		t.Fatalf("updateRecord() error = %v", err)
+	}
+	if output != "mock output" {
+		t.Errorf("expected output %q, got %q", "mock output", output)
+	}
+}
+
+// MockTXTProvider implements both Provider and TXTProvider for testing the
+// ACME DNS-01 endpoints.
+type MockTXTProvider struct {
+	MockProvider
+	presented map[string]string
+}
+
+func (m *MockTXTProvider) PresentTXT(fqdn, value string) error {
+	if m.presented == nil {
+		m.presented = make(map[string]string)
+	}
+	m.presented[fqdn] = value
+	return nil
+}
+
+func (m *MockTXTProvider) CleanupTXT(fqdn, _ string) error {
+	delete(m.presented, fqdn)
+	return nil
+}
+
+func dns01Req(t *testing.T, fqdn, value string) *http.Request {
+	t.Helper()
+	body, err := json.Marshal(dns01Request{FQDN: fqdn, Value: value})
+	if err != nil {
+		t.Fatalf("failed to marshal request: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/dns-01/present", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", testUsername, testPassword))))
+	return req
+}
+
+func TestDNS01PresentHandler_Success(t *testing.T) {
+	mockProvider := &MockTXTProvider{}
+	server := newTestServer(mockProvider)
+
+	req := dns01Req(t, "home.example.com", "challenge-value")
+	rw := httptest.NewRecorder()
+	server.dns01PresentHandler(rw, req)
+
+	resp := rw.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 OK, got %d", resp.StatusCode)
+	}
+	if got := mockProvider.presented["_acme-challenge.home.example.com."]; got != "challenge-value" {
+		t.Errorf("expected challenge TXT record to be present, got %q", got)
+	}
+}
+
+func TestDNS01CleanupHandler_Success(t *testing.T) {
+	mockProvider := &MockTXTProvider{presented: map[string]string{"_acme-challenge.home.example.com.": "challenge-value"}}
+	server := newTestServer(mockProvider)
+
+	req := dns01Req(t, "home.example.com", "challenge-value")
+	rw := httptest.NewRecorder()
+	server.dns01CleanupHandler(rw, req)
+
+	resp := rw.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 OK, got %d", resp.StatusCode)
+	}
+	if _, ok := mockProvider.presented["_acme-challenge.home.example.com."]; ok {
+		t.Error("expected challenge TXT record to be removed")
+	}
+}
+
+func TestDNS01Handler_UnsupportedProvider(t *testing.T) {
+	mockProvider := &MockProvider{}
+	server := newTestServer(mockProvider)
+
+	req := dns01Req(t, "home.example.com", "challenge-value")
+	rw := httptest.NewRecorder()
+	server.dns01PresentHandler(rw, req)
+
+	resp := rw.Result()
+	if resp.StatusCode != http.StatusNotImplemented {
+		t.Fatalf("expected 501 Not Implemented, got %d", resp.StatusCode)
+	}
+}
+
+func TestDNS01Handler_BadAuth(t *testing.T) {
+	mockProvider := &MockTXTProvider{}
+	server := newTestServer(mockProvider)
+
+	req := dns01Req(t, "home.example.com", "challenge-value")
+	req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte("bad:creds")))
+	rw := httptest.NewRecorder()
+	server.dns01PresentHandler(rw, req)
+
+	resp := rw.Result()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 Unauthorized, got %d", resp.StatusCode)
+	}
+}
+
+func TestDynDNSUpdateHandler_UnknownUser(t *testing.T) {
+	mockProvider := &MockProvider{}
+	server := newTestServer(mockProvider)
+
+	req := httptest.NewRequest("GET", "/nic/update?hostname=test.example.com&myip=1.2.3.4", nil)
+	req.Header.Set("User-Agent", validUserAgent)
+	req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte("nobody:pass")))
+
+	rw := httptest.NewRecorder()
+	server.dynDNSUpdateHandler(rw, req)
+
+	resp := rw.Result()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 Unauthorized, got %d", resp.StatusCode)
+	}
+	if mockProvider.updateCalled {
+		t.Error("expected provider.UpdateRecord not to be called for an unknown user")
+	}
+}
+
+func TestDynDNSUpdateHandler_DisallowedHostname(t *testing.T) {
+	mockProvider := &MockProvider{}
+	server := newTestServer(mockProvider, "home.example.com")
+
+	req := httptest.NewRequest("GET", "/nic/update?hostname=other.example.com&myip=1.2.3.4", nil)
+	req.Header.Set("User-Agent", validUserAgent)
+	req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", testUsername, testPassword))))
+
+	rw := httptest.NewRecorder()
+	server.dynDNSUpdateHandler(rw, req)
+
+	resp := rw.Result()
+	body, _ := io.ReadAll(resp.Body)
+	if got := string(body); got != MsgNoHost+"\n" {
+		t.Errorf("expected %q, got %q", MsgNoHost+"\n", got)
+	}
+	if mockProvider.updateCalled {
+		t.Error("expected provider.UpdateRecord not to be called for a disallowed hostname")
+	}
+}
+
+func TestDynDNSUpdateHandler_WildcardSuffixAllowed(t *testing.T) {
+	mockProvider := &MockProvider{}
+	server := newTestServer(mockProvider, "*.home.example.com")
+
+	req := httptest.NewRequest("GET", "/nic/update?hostname=office.home.example.com&myip=1.2.3.4", nil)
+	req.Header.Set("User-Agent", validUserAgent)
+	req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", testUsername, testPassword))))
+
+	rw := httptest.NewRecorder()
+	server.dynDNSUpdateHandler(rw, req)
+
+	resp := rw.Result()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 OK, got %d", resp.StatusCode)
+	}
+	if !mockProvider.updateCalled {
+		t.Error("expected provider.UpdateRecord to be called for a hostname matching the wildcard suffix")
+	}
+}
+
+func TestDynDNSUpdateHandler_WildcardSuffixDoesNotMatchApex(t *testing.T) {
+	mockProvider := &MockProvider{}
+	server := newTestServer(mockProvider, "*.home.example.com")
+
+	req := httptest.NewRequest("GET", "/nic/update?hostname=home.example.com&myip=1.2.3.4", nil)
+	req.Header.Set("User-Agent", validUserAgent)
+	req.Header.Set("Authorization", "Basic "+base64.StdEncoding.EncodeToString([]byte(fmt.Sprintf("%s:%s", testUsername, testPassword))))
+
+	rw := httptest.NewRecorder()
+	server.dynDNSUpdateHandler(rw, req)
+
+	resp := rw.Result()
+	body, _ := io.ReadAll(resp.Body)
+	if got := string(body); got != MsgNoHost+"\n" {
+		t.Errorf("expected %q, got %q", MsgNoHost+"\n", got)
+	}
+}